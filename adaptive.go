@@ -0,0 +1,56 @@
+package main
+
+import "time"
+
+// defaultAdaptiveMax is the longest -adaptive lets an idle port go between
+// reads unless -adaptive-max says otherwise.
+const defaultAdaptiveMax = 10 * time.Second
+
+// adaptivePoll schedules a local port's reads for -adaptive, which spends
+// fewer syscalls on ports with nothing on them. The backoff is
+// multiplicative: each read that finds the port idle (both directions below
+// idleThresholdGbps) doubles the gap to its next read, from the -interval
+// up to -adaptive-max, and any traffic drops it straight back to the
+// interval. Ticks keep their cadence; a port that is not yet due is skipped
+// and keeps showing its last reading, so traffic starting on an idle port
+// shows up at most one gap late.
+type adaptivePoll struct {
+	gap  time.Duration // between the last read and the next; 0 before the first
+	last time.Time     // time of the last read
+	next time.Time     // when the port is next due
+}
+
+// due reports whether the port is to be read on the tick at now. Ticks can
+// fire a little early or late, so a read due within half an interval of
+// now is taken.
+func (p adaptivePoll) due(now time.Time, interval time.Duration) bool {
+	return p.next.IsZero() || !now.Add(interval/2).Before(p.next)
+}
+
+// elapsed returns the time a read at now covers: since the last read, or
+// the interval for the first.
+func (p adaptivePoll) elapsed(now time.Time, interval time.Duration) time.Duration {
+	if p.last.IsZero() {
+		return interval
+	}
+	return now.Sub(p.last)
+}
+
+// schedule records a read at now and sets the next one, backing off while
+// the port is idle as described on adaptivePoll.
+func (p *adaptivePoll) schedule(now time.Time, idle bool, interval, ceiling time.Duration) {
+	if idle && p.gap > 0 {
+		p.gap = min(ceiling, 2*p.gap)
+	} else {
+		p.gap = interval
+	}
+	p.last = now
+	p.next = now.Add(p.gap)
+}
+
+// adaptivePolled reports whether -adaptive schedules the port's reads. Only
+// sysfs reads are spaced out; -remote and -replay readings cost nothing to
+// take.
+func (m model) adaptivePolled(s ifaceStatus) bool {
+	return m.adaptive && s.host == nil && s.replay == nil
+}
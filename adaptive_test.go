@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptivePoll(t *testing.T) {
+	const interval, ceiling = time.Second, 5 * time.Second
+	start := time.Unix(1000, 0)
+	var p adaptivePoll
+	if !p.due(start, interval) || p.elapsed(start, interval) != interval {
+		t.Fatal("a port never read is due, covering one interval")
+	}
+
+	// Idle reads double the gap up to the ceiling.
+	now := start
+	for _, want := range []time.Duration{1, 2, 4, 5, 5} {
+		p.schedule(now, true, interval, ceiling)
+		if p.gap != want*time.Second {
+			t.Fatalf("gap after idle read at %v = %v, want %v", now.Sub(start), p.gap, want*time.Second)
+		}
+		if p.due(now.Add(p.gap-interval), interval) {
+			t.Errorf("due a tick before the gap of %v is up", p.gap)
+		}
+		// A tick firing slightly early still takes the read.
+		now = now.Add(p.gap - time.Millisecond)
+		if !p.due(now, interval) {
+			t.Errorf("not due at the end of a gap of %v", p.gap)
+		}
+		if got := p.elapsed(now, interval); got != p.gap-time.Millisecond {
+			t.Errorf("elapsed = %v, want %v", got, p.gap-time.Millisecond)
+		}
+	}
+
+	// Traffic brings it straight back to the interval.
+	p.schedule(now, false, interval, ceiling)
+	if p.gap != interval {
+		t.Errorf("gap after a busy read = %v, want %v", p.gap, interval)
+	}
+}
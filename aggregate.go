@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// aggGroup is a named set of ports, such as the rails of a multi-rail job,
+// shown as one summed row.
+type aggGroup struct {
+	name    string
+	members []string // "adaptor:port", or "host adaptor:port" with -remote
+}
+
+// aggGroupFlags collects repeated -group name=mlx5_0:1+mlx5_1:1 flags. A
+// single value may also hold several comma-separated groups, as produced by
+// a config-file list.
+type aggGroupFlags []aggGroup
+
+func (g *aggGroupFlags) String() string {
+	var specs []string
+	for _, group := range *g {
+		specs = append(specs, group.name+"="+strings.Join(group.members, "+"))
+	}
+	return strings.Join(specs, ",")
+}
+
+func (g *aggGroupFlags) Set(value string) error {
+	for _, spec := range strings.Split(value, ",") {
+		name, members, ok := strings.Cut(strings.TrimSpace(spec), "=")
+		if !ok || name == "" || members == "" {
+			return fmt.Errorf("invalid group %q: want name=adaptor:port+adaptor:port", spec)
+		}
+		group := aggGroup{name: name}
+		for _, member := range strings.Split(members, "+") {
+			if member = strings.TrimSpace(member); member != "" {
+				group.members = append(group.members, member)
+			}
+		}
+		*g = append(*g, group)
+	}
+	return nil
+}
+
+// matches reports whether stat is one of the group's members.
+func (g aggGroup) matches(stat ifaceStatus) bool {
+	for _, member := range g.members {
+		if member == stat.name() || member == stat.iface.Adaptor+":"+stat.iface.Port {
+			return true
+		}
+	}
+	return false
+}
+
+// validateAggGroups checks that every group member names a monitored port.
+func validateAggGroups(groups []aggGroup, statuses []ifaceStatus) error {
+	for _, g := range groups {
+		for _, member := range g.members {
+			single := aggGroup{members: []string{member}}
+			found := false
+			for _, stat := range statuses {
+				if single.matches(stat) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("group %s: unknown interface %q", g.name, member)
+			}
+		}
+	}
+	return nil
+}
+
+// aggMember reports whether stat belongs to any aggregation group.
+func (m model) aggMember(stat ifaceStatus) bool {
+	for _, g := range m.aggGroups {
+		if g.matches(stat) {
+			return true
+		}
+	}
+	return false
+}
+
+// renderAggGroups renders one summed row per aggregation group, its
+// percentages taken against the members' combined line rate.
+func (m model) renderAggGroups(hostWidth int) string {
+	var b strings.Builder
+	for _, g := range m.aggGroups {
+		var members []ifaceStatus
+		for _, stat := range m.statuses {
+			if g.matches(stat) {
+				members = append(members, stat)
+			}
+		}
+		sum := sumStatuses(members)
+		sum.host = nil // groups may span hosts
+		b.WriteString(m.renderRow(g.name, sum, hostWidth, false) + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+)
+
+// alerter raises -bell and -notify alerts when a port turns critical: when
+// either direction reaches critPct of line rate, or when its error counters
+// rise. Only the transition into the critical state alerts, and a port
+// alerts at most once per debounce interval, so a sustained or flapping
+// condition does not spam. It is an emitter, fed the ports sampled in each
+// snapshot.
+type alerter struct {
+	bell     io.Writer // terminal the bell is rung on; nil disables -bell
+	notify   bool      // run notify-send for each alert
+	critPct  float64   // percent of line rate that is critical; 0 disables
+	debounce time.Duration
+
+	ports   map[string]*alertState // keyed by ifaceSnapshot.name
+	pending string                 // latest alert not yet shown, see take
+}
+
+// alertState tracks one port between samples.
+type alertState struct {
+	critical  bool
+	alertedAt time.Time
+	errors    int64 // sum of the error counters at the previous check
+	errorsOK  bool  // whether errors holds a reading
+}
+
+// newAlerter sets up alerting. With notify it fails if notify-send is not
+// installed, rather than dropping every alert later.
+func newAlerter(bell io.Writer, notify bool, critPct float64, debounce time.Duration) (*alerter, error) {
+	if notify {
+		if _, err := exec.LookPath("notify-send"); err != nil {
+			return nil, fmt.Errorf("-notify: %w", err)
+		}
+	}
+	return &alerter{
+		bell:     bell,
+		notify:   notify,
+		critPct:  critPct,
+		debounce: debounce,
+		ports:    make(map[string]*alertState),
+	}, nil
+}
+
+// emit checks every port sampled in snap.
+func (a *alerter) emit(snap snapshot) error {
+	for _, p := range snap.Interfaces {
+		if !p.sampled {
+			continue
+		}
+		if msg := a.check(p, snap.Time); msg != "" {
+			a.pending = msg
+		}
+	}
+	return nil
+}
+
+// take returns the latest alert raised since the previous take, or "", for
+// the TUI's footer.
+func (a *alerter) take() string {
+	msg := a.pending
+	a.pending = ""
+	return msg
+}
+
+// check updates p's alert state after a sample and fires an alert if it has
+// just turned critical. It returns the alert message, or "" if none was
+// raised.
+func (a *alerter) check(p ifaceSnapshot, now time.Time) string {
+	st := a.ports[p.name()]
+	if st == nil {
+		st = &alertState{}
+		a.ports[p.name()] = st
+	}
+
+	reason := a.critical(p, st)
+	wasCritical := st.critical
+	st.critical = reason != ""
+	if !st.critical || wasCritical || now.Sub(st.alertedAt) < a.debounce {
+		return ""
+	}
+	st.alertedAt = now
+
+	msg := p.name() + " " + reason
+	if a.bell != nil {
+		_, _ = io.WriteString(a.bell, "\a")
+	}
+	if a.notify {
+		cmd := exec.Command("notify-send", "ibmon", msg)
+		if err := cmd.Start(); err == nil {
+			go cmd.Wait()
+		}
+	}
+	return msg
+}
+
+// critical returns why p is critical, or "" if it is not. It does no I/O:
+// error counters only count when readPorts read them, which it does for
+// local ports alone.
+func (a *alerter) critical(p ifaceSnapshot, st *alertState) string {
+	var reason string
+	if a.critPct > 0 && p.MaxGbps > 0 {
+		switch {
+		case p.RxGbps >= p.MaxGbps*a.critPct/100:
+			reason = fmt.Sprintf("RX at %.0f%% of line rate", p.RxGbps/p.MaxGbps*100)
+		case p.TxGbps >= p.MaxGbps*a.critPct/100:
+			reason = fmt.Sprintf("TX at %.0f%% of line rate", p.TxGbps/p.MaxGbps*100)
+		}
+	}
+
+	if p.errors == nil {
+		return reason
+	}
+	var sum int64
+	for _, c := range p.errors {
+		sum += c.Value
+	}
+	if st.errorsOK && sum > st.errors && reason == "" {
+		reason = fmt.Sprintf("error counters rose by %d", sum-st.errors)
+	}
+	st.errors, st.errorsOK = sum, true
+	return reason
+}
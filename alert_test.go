@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apsu/ibmon/ibmon"
+)
+
+func TestAlertErrorCounters(t *testing.T) {
+	a, err := newAlerter(nil, false, 0, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	port := func(errors int64) ifaceSnapshot {
+		p := ifaceSnapshot{Adaptor: "mlx5_0", Port: "1", MaxGbps: 400, sampled: true}
+		if errors >= 0 {
+			p.errors = []ibmon.Counter{{Name: "symbol_error", Value: errors}}
+		}
+		return p
+	}
+
+	if msg := a.check(port(5), now); msg != "" {
+		t.Errorf("first reading alerted: %q", msg)
+	}
+	// A tick whose error counters were not read leaves the baseline alone.
+	if msg := a.check(port(-1), now.Add(time.Second)); msg != "" {
+		t.Errorf("unread counters alerted: %q", msg)
+	}
+	if err := a.emit(snapshot{Time: now.Add(2 * time.Second), Interfaces: []ifaceSnapshot{port(7)}}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := a.take(); msg != "mlx5_0:1 error counters rose by 2" {
+		t.Errorf("rising counters: got %q", msg)
+	}
+	if msg := a.take(); msg != "" {
+		t.Errorf("take again: got %q, want nothing", msg)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// portBars are a port's RX and TX bars in the split layout. Unlike the bars
+// drawn with ViewAs they persist from one sample to the next, so each can
+// glide from the previous value to the new one.
+type portBars struct {
+	rx, tx progress.Model
+}
+
+func newPortBars(chars barChars) *portBars {
+	return &portBars{
+		rx: progress.New(progress.WithDefaultGradient(), chars.option()),
+		tx: progress.New(progress.WithDefaultGradient(), chars.option()),
+	}
+}
+
+// animateBars points every port's bars at its latest rates and returns the
+// commands that animate them there. It does nothing with -no-animation or
+// outside the split layout, where rows are drawn at their values directly.
+// The animation runs on its own frame messages, so the sampling ticks keep
+// their cadence; a sample arriving mid-animation just retargets the bars.
+func (m *model) animateBars() tea.Cmd {
+	if !m.animate || m.layout != layoutSplit {
+		return nil
+	}
+	var cmds []tea.Cmd
+	for i := range m.statuses {
+		stat := &m.statuses[i]
+		if stat.bars == nil {
+			stat.bars = newPortBars(m.barChars)
+		}
+		rx, tx := stat.displayValues()
+		cmds = append(cmds,
+			stat.bars.rx.SetPercent(barPosition(lineFraction(rx, stat.iface.MaxGbps), m.logScale)),
+			stat.bars.tx.SetPercent(barPosition(lineFraction(tx, stat.iface.MaxGbps), m.logScale)))
+	}
+	return tea.Batch(cmds...)
+}
+
+// updateBars forwards an animation frame to the bar it belongs to and
+// returns the command for that bar's next frame, if it is still moving.
+func (m *model) updateBars(msg progress.FrameMsg) tea.Cmd {
+	for _, stat := range m.statuses {
+		if stat.bars == nil {
+			continue
+		}
+		for _, bar := range []*progress.Model{&stat.bars.rx, &stat.bars.tx} {
+			updated, cmd := bar.Update(msg)
+			*bar = updated.(progress.Model)
+			if cmd != nil {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+// barViews renders a port's RX and TX bars at the given width: the animated
+// bars if the port has them, otherwise bars drawn at rxPct and txPct of line
+// rate. Under -log-scale the bars leave out the percentage they would
+// append, which would be of their scaled position.
+func (m model) barViews(stat ifaceStatus, width int, rxPct, txPct float64) (rx, tx string) {
+	if stat.bars == nil {
+		bar := progress.New(progress.WithDefaultGradient(), progress.WithWidth(width), m.barChars.option())
+		bar.ShowPercentage = !m.logScale
+		return bar.ViewAs(barPosition(rxPct, m.logScale)), bar.ViewAs(barPosition(txPct, m.logScale))
+	}
+	rxBar, txBar := stat.bars.rx, stat.bars.tx
+	rxBar.Width, txBar.Width = width, width
+	rxBar.ShowPercentage, txBar.ShowPercentage = !m.logScale, !m.logScale
+	return rxBar.View(), txBar.View()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// auditLog appends every distinct frame of the port display to a file as
+// plain text, for -audit: a verbatim record of what was on screen, where
+// -logfile records the figures behind it.
+type auditLog struct {
+	f    *os.File
+	last string // the last frame written
+}
+
+// newAuditLog opens path for appending frames.
+func newAuditLog(path string) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &auditLog{f: f}, nil
+}
+
+// write appends content, stripped of ANSI escapes, under a timestamp line.
+// A frame identical to the last one written is skipped, so the file only
+// grows when the display does change.
+func (a *auditLog) write(content string, now time.Time) error {
+	frame := stripANSI(content)
+	if frame == a.last {
+		return nil
+	}
+	if _, err := fmt.Fprintf(a.f, "--- %s\n%s\n", now.Format(time.RFC3339Nano), strings.TrimRight(frame, "\n")); err != nil {
+		return err
+	}
+	a.last = frame
+	return nil
+}
+
+func (a *auditLog) Close() error {
+	if err := a.f.Sync(); err != nil {
+		a.f.Close()
+		return err
+	}
+	return a.f.Close()
+}
+
+// ansiSeq matches the CSI escape sequences lipgloss and the progress bars
+// style text with.
+var ansiSeq = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]`)
+
+// stripANSI removes terminal escape sequences from s.
+func stripANSI(s string) string {
+	return ansiSeq.ReplaceAllString(s, "")
+}
+
+// auditFrame writes the current content to the -audit log, if there is
+// one. The bars are drawn at their sampled values rather than mid-animation,
+// so the frames of an animation are not recorded one by one.
+func (m *model) auditFrame() {
+	if m.audit == nil {
+		return
+	}
+	static := *m
+	static.statuses = slices.Clone(m.statuses)
+	for i := range static.statuses {
+		static.statuses[i].bars = nil
+	}
+	if err := m.audit.write(static.renderContent(), time.Now()); err != nil {
+		m.setNotice("audit: " + err.Error())
+	}
+}
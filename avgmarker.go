@@ -0,0 +1,117 @@
+package main
+
+import (
+	"cmp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// avgMarkerChar is drawn in a bar at the position of its -avg-window average
+// with -avg-marker or the 'o' key, over the fill of the current value.
+const avgMarkerChar = '│'
+
+var avgMarkerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF")).Bold(true)
+
+// barCells returns the number of cells of the bar that rendered starts with:
+// the run of chars' fill and empty characters, not counting the percentage
+// a progress bar appends.
+func barCells(rendered string, chars barChars) int {
+	c := cmp.Or(chars, defaultBarChars)
+	n := 0
+	for _, r := range stripANSI(rendered) {
+		if r != c.full && r != c.empty {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// markCell returns rendered with its visible character at index cell
+// replaced by the average marker. Escape sequences are copied through, and
+// the ones in effect at the marker are repeated after it, so every other
+// cell keeps its column and its gradient or solid color.
+func markCell(rendered string, cell int) string {
+	var b strings.Builder
+	var active string // escapes in effect since the last reset
+	n := 0
+	write := func(text string) {
+		for _, r := range text {
+			if n == cell {
+				b.WriteString(avgMarkerStyle.Render(string(avgMarkerChar)))
+				b.WriteString(active)
+			} else {
+				b.WriteRune(r)
+			}
+			n++
+		}
+	}
+	prev := 0
+	for _, loc := range ansiSeq.FindAllStringIndex(rendered, -1) {
+		write(rendered[prev:loc[0]])
+		seq := rendered[loc[0]:loc[1]]
+		b.WriteString(seq)
+		if seq == "\x1b[0m" || seq == "\x1b[m" {
+			active = ""
+		} else {
+			active += seq
+		}
+		prev = loc[1]
+	}
+	write(rendered[prev:])
+	return b.String()
+}
+
+// markAverage marks a rendered bar at frac of its length.
+func markAverage(rendered string, frac float64, chars barChars) string {
+	cells := barCells(rendered, chars)
+	if cells == 0 {
+		return rendered
+	}
+	return markCell(rendered, min(cells-1, int(frac*float64(cells))))
+}
+
+// markCombined marks a combinedBar of the given width at rxFrac of its RX
+// half, counted from the left edge, and txFrac of its TX half, counted from
+// the right.
+func markCombined(rendered string, width int, rxFrac, txFrac float64) string {
+	rxHalf := width / 2
+	txHalf := width - rxHalf
+	if rxHalf == 0 {
+		return rendered
+	}
+	rendered = markCell(rendered, min(rxHalf-1, int(rxFrac*float64(rxHalf))))
+	return markCell(rendered, width-1-min(txHalf-1, int(txFrac*float64(txHalf))))
+}
+
+// averagePositions returns where along the bars a port's RX and TX
+// averages go.
+func (m model) averagePositions(stat ifaceStatus) (rx, tx float64) {
+	return barPosition(lineFraction(stat.rxAvg, stat.iface.MaxGbps), m.logScale),
+		barPosition(lineFraction(stat.txAvg, stat.iface.MaxGbps), m.logScale)
+}
+
+// markAverages marks a port's rendered RX and TX bars at its averages if
+// the marker is on.
+func (m model) markAverages(stat ifaceStatus, rxBar, txBar string) (string, string) {
+	if !m.showAvgMarker {
+		return rxBar, txBar
+	}
+	rx, tx := m.averagePositions(stat)
+	return markAverage(rxBar, rx, m.barChars), markAverage(txBar, tx, m.barChars)
+}
+
+// toggleAvgMarker is the 'o' key. The marker needs an average to mark, so it
+// stays off without -avg-window.
+func (m *model) toggleAvgMarker() {
+	if m.avgWindow <= 0 {
+		m.setNotice("the average marker needs -avg-window")
+		return
+	}
+	if m.noBars {
+		m.setNotice("no bars to mark with -no-bars")
+		return
+	}
+	m.showAvgMarker = !m.showAvgMarker
+}
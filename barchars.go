@@ -0,0 +1,53 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/progress"
+)
+
+// barChars are the runes bars are drawn with: one for the filled part and
+// one for the empty part.
+type barChars struct {
+	full, empty rune
+}
+
+// defaultBarChars are the block characters of the progress bars.
+var defaultBarChars = barChars{'█', '░'}
+
+// barPresets are the named character sets -bar-chars accepts besides two
+// literal characters.
+var barPresets = map[string]barChars{
+	"blocks": defaultBarChars,
+	"shade":  {'█', '░'},
+	"ascii":  {'#', '-'},
+}
+
+// parseBarChars parses -bar-chars: a preset name, or exactly two characters,
+// the fill and then the empty one, e.g. "=.".
+func parseBarChars(s string) (barChars, error) {
+	if c, ok := barPresets[s]; ok {
+		return c, nil
+	}
+	r := []rune(s)
+	if len(r) != 2 {
+		return barChars{}, fmt.Errorf("invalid -bar-chars %q: want blocks, shade, ascii or two characters (fill, then empty)", s)
+	}
+	return barChars{full: r[0], empty: r[1]}, nil
+}
+
+// option returns the progress option drawing a bar with c, or with the
+// default characters if c is unset.
+func (c barChars) option() progress.Option {
+	c = cmp.Or(c, defaultBarChars)
+	return progress.WithFillCharacters(c.full, c.empty)
+}
+
+// fill returns filled full characters and empty empty ones, for the bars
+// drawn without the progress package.
+func (c barChars) fill(filled, empty int) (string, string) {
+	c = cmp.Or(c, defaultBarChars)
+	return strings.Repeat(string(c.full), filled), strings.Repeat(string(c.empty), empty)
+}
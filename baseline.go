@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// baselineRates are a port's rates when the 'b' key marked the baseline.
+type baselineRates struct {
+	rx, tx float64
+}
+
+// Colors of a rate's change from the baseline: up is green, down red.
+var (
+	gainStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#5FD75F"))
+	lossStyle = lipgloss.NewStyle().Foreground(critColor)
+)
+
+// toggleBaseline is the 'b' key. It marks every port's current rates as
+// the baseline, which the rows then show their change from, e.g.
+// "Δ+0012.3", until 'b' clears it again. It only affects the display.
+func (m *model) toggleBaseline(now time.Time) {
+	if m.baseline != nil {
+		m.baseline = nil
+		m.setNotice("baseline cleared")
+		return
+	}
+	m.baseline = make(map[string]baselineRates, len(m.statuses))
+	for _, stat := range m.statuses {
+		m.baseline[stat.name()] = baselineRates{rx: stat.rxValue, tx: stat.txValue}
+	}
+	m.baselineAt = now
+	m.setNotice("baseline set ('b' clears)")
+}
+
+// baselineWidth returns the width of the change shown after each rate,
+// " Δ" and a signed number, or zero with no baseline.
+func (m model) baselineWidth() int {
+	if m.baseline == nil {
+		return 0
+	}
+	return len(" Δ+") - len("Δ") + 1 + m.numberWidth()
+}
+
+// baselineDeltas returns the change of a port's RX and TX rates from the
+// baseline, to follow the rates in its row, or blanks of the same width for
+// a port found since the baseline was marked.
+func (m model) baselineDeltas(stat ifaceStatus) (rx, tx string) {
+	base, ok := m.baseline[stat.name()]
+	if !ok {
+		blank := alignLeft("", m.baselineWidth())
+		return blank, blank
+	}
+	return m.formatDelta(stat.rxValue - base.rx), m.formatDelta(stat.txValue - base.tx)
+}
+
+// formatDelta formats a change in Gbps in the display units, colored by
+// whether the rate went up or down. A change too small to show at the
+// display precision is left uncolored.
+func (m model) formatDelta(gbps float64) string {
+	v := m.displayValue(gbps)
+	num := m.formatNumber(math.Abs(v))
+	switch {
+	case num == m.formatNumber(0):
+		return " Δ " + num
+	case v > 0:
+		return " Δ" + gainStyle.Render("+"+num)
+	default:
+		return " Δ" + lossStyle.Render("-"+num)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"time"
+
+	"github.com/apsu/ibmon/ibmon"
+)
+
+// bdpWidth is the width of the -rtt figure: " bdp " plus a 10-character
+// byte count, per direction.
+const bdpWidth = 15
+
+// inFlight returns the bandwidth-delay product of a direction running at
+// gbps with a round trip of rtt: the bytes in flight that buffers and send
+// windows have to cover. It simply assumes the current rate holds for the
+// whole round trip, so it is a rough figure for sizing discussions rather
+// than a measurement.
+func inFlight(gbps float64, rtt time.Duration) uint64 {
+	return uint64(gbps * ibmon.BitsPerGbit / 8 * rtt.Seconds())
+}
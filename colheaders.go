@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var columnHeaderStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#808080")).Bold(true)
+
+// alignLeft and alignRight pad or cut s to exactly width columns.
+func alignLeft(s string, width int) string  { return fmt.Sprintf("%-*.*s", width, max(0, width), s) }
+func alignRight(s string, width int) string { return fmt.Sprintf("%*.*s", width, max(0, width), s) }
+
+// columnHeaders returns the line of column labels pinned above the rows
+// with -headers or the 'L' key, laid out with the same widths as renderRow
+// so that each label sits over its column, or "" if they are off. The
+// compact rows of a narrow terminal and the cells of -fit have none.
+func (m model) columnHeaders() string {
+	if !m.showHeaders || m.fit {
+		return ""
+	}
+	if cols := m.columnCount(); cols > 1 && !m.grouped {
+		// The same labels over every column.
+		cell := m.inColumn(cols)
+		h := cell.columnHeaders()
+		if h == "" {
+			return ""
+		}
+		return strings.Repeat(lipgloss.PlaceHorizontal(cell.termWidth, lipgloss.Left, h)+columnGap, cols-1) + h
+	}
+	hostWidth := m.hostWidth()
+	available, dirs := m.barSpace(hostWidth)
+	prefix := alignLeft("", hostWidth)
+	if hostWidth > 0 {
+		prefix = alignLeft("HOST", hostWidth)
+	}
+	valueWidth := m.valueWidth()
+	rate := func(dir string) string {
+		label := dir + " RATE"
+		if m.avgWindow > 0 {
+			label += " (AVG)"
+		}
+		if m.baseline != nil {
+			label += " Δ"
+		}
+		if m.rtt > 0 {
+			label += " BDP"
+		}
+		if m.showTotals {
+			label += " Σ TOTAL"
+		}
+		return alignLeft(label, valueWidth)
+	}
+
+	var line string
+	switch {
+	case m.minimal:
+		// Name, bar and percentage, as in renderMinimal.
+		barWidth := max(5, m.termWidth-hostWidth-17)
+		line = prefix + alignLeft("PORT", 10) + " " + alignLeft("UTILIZATION", barWidth) + " " + alignRight("%", 5)
+	case m.noBars:
+		line = prefix + alignLeft("PORT (LINE RATE)", m.headerWidth(hostWidth)-hostWidth) + m.noBarsHeaders(rate)
+	case available < dirs*minBarWidth:
+		return ""
+	case dirs == 1:
+		dir := "RX"
+		if m.direction == dirTX {
+			dir = "TX"
+		}
+		// "↓ " + bar + " " + percent + " " + rate
+		line = prefix + alignLeft("PORT (LINE RATE)", m.headerWidth(hostWidth)-hostWidth) +
+			"  " + alignLeft(dir, available) + " " + alignRight(dir+"%", 5) + " " + rate(dir)
+	case m.layout == layoutCombined:
+		// "↓ " + rate + " " + percent + " " + bar + " " + percent + " " + rate + " ↑"
+		line = prefix + alignLeft("PORT (LINE RATE)", m.headerWidth(hostWidth)-hostWidth) +
+			"  " + rate("RX") + " " + alignRight("RX%", 5) + " " + alignLeft("RX", available/2) + alignRight("TX", available-available/2) +
+			" " + alignRight("TX%", 5) + " " + rate("TX")
+	default:
+		// "↓ " + bar + " " + percent + " " + rate + "   ↑ " + bar + " " + percent + " " + rate
+		barWidth := available / 2
+		line = prefix + alignLeft("PORT (LINE RATE)", m.headerWidth(hostWidth)-hostWidth) +
+			"  " + alignLeft("RX", barWidth) + " " + alignRight("RX%", 5) + " " + rate("RX") +
+			"     " + alignLeft("TX", barWidth) + " " + alignRight("TX%", 5) + " " + rate("TX")
+	}
+	return columnHeaderStyle.Render(strings.TrimRight(line, " "))
+}
+
+// pinned returns what View draws above the viewport: the column headers and
+// a line break, or "" without them.
+func (m model) pinned() string {
+	if h := m.columnHeaders(); h != "" {
+		return h + "\n"
+	}
+	return ""
+}
+
+// pinnedHeight returns the number of lines pinned above the viewport.
+func (m model) pinnedHeight() int {
+	return strings.Count(m.pinned(), "\n")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// columnGap separates the columns of -columns.
+const columnGap = "   "
+
+// Column widths for -columns: no column is laid out narrower than
+// minColumnWidth, whatever was asked for, and the automatic layout aims for
+// columns of autoColumnWidth.
+const (
+	minColumnWidth  = 100
+	autoColumnWidth = 140
+)
+
+// columnsAuto is -columns 0: as many columns as the terminal has room for.
+const columnsAuto = -1
+
+// columnCount returns how many columns the flat view lays its rows out in:
+// as many as -columns asks for, or for columnsAuto as many of
+// autoColumnWidth as fit, but only as many as the terminal is wide enough
+// for, so a narrow one falls back to a single column.
+func (m model) columnCount() int {
+	n := m.columns
+	if n == columnsAuto {
+		n = (m.termWidth + len(columnGap)) / (autoColumnWidth + len(columnGap))
+	}
+	return max(1, min(n, (m.termWidth+len(columnGap))/(minColumnWidth+len(columnGap))))
+}
+
+// inColumn returns a copy of m that renders rows for one of cols columns,
+// as if the terminal were only as wide as the column.
+func (m model) inColumn(cols int) model {
+	m.termWidth = (m.termWidth - (cols-1)*len(columnGap)) / cols
+	m.columns = 0
+	return m
+}
+
+// renderColumns renders the flat view's rows in cols columns side by side,
+// filled top to bottom, and returns the line on which the selected row
+// starts, as renderPorts does.
+func (m model) renderColumns(hostWidth, cols int) (string, int) {
+	shown, more := m.shownPorts()
+	cell := m.inColumn(cols)
+	rows := (len(shown) + cols - 1) / cols
+
+	var b strings.Builder
+	selectedLine, line := 0, 0
+	for r := range rows {
+		var blocks []string
+		for c := range cols {
+			n := c*rows + r
+			if n >= len(shown) {
+				break
+			}
+			if shown[n] == m.selected {
+				selectedLine = line
+			}
+			if c > 0 {
+				blocks = append(blocks, columnGap)
+			}
+			blocks = append(blocks, lipgloss.PlaceHorizontal(cell.termWidth, lipgloss.Left, cell.renderPort(shown[n], hostWidth)))
+		}
+		joined := lipgloss.JoinHorizontal(lipgloss.Top, blocks...)
+		b.WriteString(joined + "\n")
+		line += lipgloss.Height(joined)
+	}
+	if more > 0 {
+		b.WriteString(footerStyle.Render(fmt.Sprintf("(… +%d more)", more)) + "\n")
+	}
+	return b.String(), selectedLine
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigPath returns the config file consulted when -config is not
+// given: $XDG_CONFIG_HOME/ibmon/config.yaml, falling back to
+// ~/.config/ibmon/config.yaml.
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "ibmon", "config.yaml")
+}
+
+// loadConfig reads a YAML file whose keys mirror the command-line flag names,
+// e.g.
+//
+//	interval: 500ms
+//	ignore: [mlx5_2, mlx5_3]
+//	hide-idle: true
+//
+// and applies each value to flags unless that flag was set explicitly on the
+// command line. Unknown keys are reported with a warning and otherwise ignored.
+// If required is false, a missing file is not an error.
+func loadConfig(flags *flag.FlagSet, path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !required && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	var values map[string]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	for key, value := range values {
+		if key == "config" || flags.Lookup(key) == nil {
+			log.Printf("warning: %s: unknown config key %q", path, key)
+			continue
+		}
+		if explicit[key] {
+			continue
+		}
+		if err := flags.Set(key, configValue(value)); err != nil {
+			return fmt.Errorf("%s: %s: %w", path, key, err)
+		}
+	}
+	return nil
+}
+
+// envPrefix starts the name of every environment variable loadEnv reads.
+const envPrefix = "IBMON_"
+
+// envName returns the environment variable for a flag: IBMON_ followed by
+// the flag name upper-cased with dashes as underscores, e.g. IBMON_HIDE_IDLE
+// for -hide-idle.
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// loadEnv applies IBMON_* variables from environ (as from os.Environ) to
+// flags, unless the flag was set explicitly on the command line. Run before
+// loadConfig, a variable also takes precedence over the config file, giving
+// command line > environment > config file > built-in default. Variables
+// that name no flag are reported with a warning and otherwise ignored.
+func loadEnv(flags *flag.FlagSet, environ []string) error {
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	byEnv := make(map[string]string)
+	flags.VisitAll(func(f *flag.Flag) {
+		byEnv[envName(f.Name)] = f.Name
+	})
+
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(key, envPrefix) {
+			continue
+		}
+		name, ok := byEnv[key]
+		if !ok {
+			log.Printf("warning: unknown environment variable %s", key)
+			continue
+		}
+		if explicit[name] {
+			continue
+		}
+		if err := flags.Set(name, value); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// configValue converts a decoded YAML value to its flag string form. Lists
+// become comma-separated strings so they can feed flags like -ignore.
+func configValue(value any) string {
+	if list, ok := value.([]any); ok {
+		parts := make([]string, len(list))
+		for i, v := range list {
+			parts[i] = fmt.Sprint(v)
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(value)
+}
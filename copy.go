@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// copiedMsg reports how copySnapshot went: path is the file the snapshot
+// was written to instead when there was no clipboard to copy it to.
+type copiedMsg struct {
+	path string
+	err  error
+}
+
+// copyText returns the plain-text snapshot the 'y' key copies: when and
+// where it was taken, the current figures of every port as in -plain, and
+// the run's peaks, averages and totals as in the quit summary.
+func (m model) copyText(now time.Time) string {
+	var b strings.Builder
+	hostname, _ := os.Hostname()
+	b.WriteString("ibmon " + hostname + " " + now.Format(time.RFC3339) + "\n\n")
+	b.WriteString(m.renderTable() + "\n")
+	m.writeSummary(&b)
+	return b.String()
+}
+
+// copySnapshot copies text to the system clipboard in the background. On a
+// headless host, where there is no clipboard tool to copy with, the text is
+// written to a temporary file instead.
+func copySnapshot(text string) tea.Cmd {
+	return func() tea.Msg {
+		if err := clipboard.WriteAll(text); err == nil {
+			return copiedMsg{}
+		}
+		f, err := os.CreateTemp("", "ibmon-snapshot-*.txt")
+		if err != nil {
+			return copiedMsg{err: err}
+		}
+		_, err = f.WriteString(text)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return copiedMsg{path: f.Name(), err: err}
+	}
+}
+
+// handleCopied reports the outcome of copySnapshot in the footer.
+func (m *model) handleCopied(msg copiedMsg) {
+	switch {
+	case msg.err != nil:
+		m.setNotice("copy: " + msg.err.Error())
+	case msg.path != "":
+		m.setNotice("no clipboard: snapshot written to " + msg.path)
+	default:
+		m.setNotice("snapshot copied to the clipboard")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/apsu/ibmon/ibmon"
+)
+
+// demoFrames is how many snapshots -demo generates before it loops: an
+// hour's worth at the default interval, long enough not to look repetitive.
+const demoFrames = 3600
+
+// demoPort is a simulated port for -demo and the shape of its traffic, as a
+// fraction of its line rate over time.
+type demoPort struct {
+	replayPort
+	rx, tx func(n int, rng *rand.Rand) float64
+}
+
+// sine returns a traffic shape swinging between lo and hi of line rate with
+// the given period in frames, offset by phase frames.
+func sine(lo, hi float64, period, phase int) func(int, *rand.Rand) float64 {
+	return func(n int, _ *rand.Rand) float64 {
+		s := math.Sin(2 * math.Pi * float64(n+phase) / float64(period))
+		return lo + (hi-lo)*(s+1)/2
+	}
+}
+
+// jitter returns a traffic shape wandering randomly around mean by up to
+// spread.
+func jitter(mean, spread float64) func(int, *rand.Rand) float64 {
+	return func(_ int, rng *rand.Rand) float64 {
+		return mean + spread*(2*rng.Float64()-1)
+	}
+}
+
+// bursts returns a traffic shape idling at base with bursts near line rate,
+// which cross the -crit threshold, for burst frames out of every period.
+func bursts(base float64, period, burst int) func(int, *rand.Rand) float64 {
+	return func(n int, rng *rand.Rand) float64 {
+		if n%period < burst {
+			return 0.93 + 0.07*rng.Float64()
+		}
+		return base * rng.Float64()
+	}
+}
+
+// idle is the traffic shape of a port with nothing on it.
+func idle(int, *rand.Rand) float64 { return 0 }
+
+// demoPorts are the simulated ports of -demo: a mix of line rates and of
+// steady, cyclic, bursty, one-sided and idle traffic, so that every part of
+// the display gets exercised.
+var demoPorts = []demoPort{
+	{replayPort{"", "mlx5_0", "1", 400}, sine(0.1, 0.9, 60, 0), sine(0.1, 0.8, 60, 15)},
+	{replayPort{"", "mlx5_0", "2", 400}, jitter(0.55, 0.15), jitter(0.5, 0.2)},
+	{replayPort{"", "mlx5_1", "1", 200}, bursts(0.1, 30, 6), bursts(0.05, 45, 4)},
+	{replayPort{"", "mlx5_1", "2", 200}, sine(0.6, 0.75, 20, 0), jitter(0.02, 0.02)},
+	{replayPort{"", "mlx5_2", "1", 100}, jitter(0.3, 0.3), sine(0, 0.5, 120, 40)},
+	{replayPort{"", "mlx5_3", "1", 100}, idle, idle},
+}
+
+// newDemo generates the -demo traffic as a looping recording with the given
+// interval between snapshots, played back like -replay. The generator is
+// seeded, so every run shows the same traffic.
+func newDemo(interval time.Duration) *replaySource {
+	src := &replaySource{loop: true, demo: true, pos: -1}
+	rng := rand.New(rand.NewPCG(1, 2))
+	start := time.Now()
+	for _, p := range demoPorts {
+		src.ports = append(src.ports, p.replayPort)
+	}
+	rxBytes := make([]uint64, len(demoPorts))
+	txBytes := make([]uint64, len(demoPorts))
+	for n := range demoFrames {
+		rows := make([]replayRow, len(demoPorts))
+		for i, p := range demoPorts {
+			rx := p.maxGbps * min(1, max(0, p.rx(n, rng)))
+			tx := p.maxGbps * min(1, max(0, p.tx(n, rng)))
+			rxBytes[i] += uint64(rx * ibmon.BitsPerGbit / 8 * interval.Seconds())
+			txBytes[i] += uint64(tx * ibmon.BitsPerGbit / 8 * interval.Seconds())
+			rows[i] = replayRow{rxGbps: rx, txGbps: tx, rxBytes: rxBytes[i], txBytes: txBytes[i], ok: true}
+		}
+		src.times = append(src.times, start.Add(time.Duration(n)*interval))
+		src.frames = append(src.frames, rows)
+	}
+	return src
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/apsu/ibmon/ibmon"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// detailTitleStyle renders the first line of the selected port's details.
+var detailTitleStyle = lipgloss.NewStyle().Bold(true)
+
+// portVisible reports whether stat gets its own row in the flat view.
+func (m model) portVisible(stat ifaceStatus) bool {
+	if m.hideIdle && stat.idle() {
+		return false
+	}
+	if !m.filterMatches(stat) {
+		return false
+	}
+	// Members of -group aggregates are folded into the group row unless
+	// shown individually with 'm'.
+	return m.showMembers || !m.aggMember(stat)
+}
+
+// moveSelection moves the selected row by delta visible rows, selecting the
+// first or last row when nothing (or a now-hidden row) is selected, and
+// scrolls the viewport so the selection stays in view.
+func (m *model) moveSelection(delta int) {
+	visible, _ := m.shownPorts()
+	pos := slices.Index(visible, m.selected)
+	if len(visible) == 0 {
+		return
+	}
+	switch {
+	case pos >= 0:
+		pos = max(0, min(len(visible)-1, pos+delta))
+	case delta < 0:
+		pos = len(visible) - 1
+	default:
+		pos = 0
+	}
+	m.selected = visible[pos]
+	m.readSelected()
+	m.relayout()
+	_, line := m.renderPorts(m.hostWidth())
+	m.scrollTo(line)
+}
+
+// clearSelection drops the selected row and its detail block.
+func (m *model) clearSelection() {
+	m.selected = -1
+	m.selErrors, m.selState = nil, ""
+	m.relayout()
+}
+
+// selectedStatus returns the selected port, if a visible one is selected.
+func (m model) selectedStatus() (ifaceStatus, bool) {
+	if m.grouped || m.selected < 0 || m.selected >= len(m.statuses) {
+		return ifaceStatus{}, false
+	}
+	return m.statuses[m.selected], true
+}
+
+// readSelected refreshes the link state and error counters shown for the
+// selection. It runs on selection changes and sampling ticks, so that
+// rendering the detail block reads nothing from sysfs.
+func (m *model) readSelected() {
+	m.selErrors, m.selState = nil, ""
+	stat, ok := m.selectedStatus()
+	if !ok {
+		return
+	}
+	m.selErrors, _ = stat.iface.ErrorCounters()
+	state, err := stat.iface.LinkState()
+	if err != nil {
+		state = "state unknown"
+	}
+	m.selState = state
+}
+
+// renderDetails builds the detail block for the selected port: rate, link
+// state, cumulative bytes, peaks, error and congestion counters, and with
+// 'H' its utilization histogram. It is empty when nothing is selected.
+func (m model) renderDetails() string {
+	stat, ok := m.selectedStatus()
+	if !ok {
+		return ""
+	}
+	state := cmp.Or(m.selState, "state unknown")
+	rate := dashIfEmpty(stat.iface.Rate)
+	if rateUnknown(stat.iface) {
+		rate = "(unknown rate)"
+	}
+	title := fmt.Sprintf("%s • %s • %s", stat.name(), rate, state)
+	if ids := portIDs(stat.iface); ids != "" {
+		title += " • " + ids
+	}
+	if hw := hardware(stat.iface); hw != "" {
+		title += " • " + hw
+	}
+	bytes := fmt.Sprintf("Σ RX %s  TX %s • peak RX %s  TX %s",
+		formatBytes(stat.rxTotal), formatBytes(stat.txTotal),
+		m.formatRate(stat.rxPeak), m.formatRate(stat.txPeak))
+
+	errs := "errors: n/a"
+	if len(m.selErrors) > 0 {
+		parts := make([]string, len(m.selErrors))
+		for i, c := range m.selErrors {
+			parts[i] = fmt.Sprintf("%s %d", c.Name, c.Value)
+		}
+		errs = "errors: " + strings.Join(parts, " • ")
+	}
+	wrap := lipgloss.NewStyle().Width(m.termWidth)
+	details := detailTitleStyle.Render(title) + "\n" + wrap.Render(bytes) + "\n" + wrap.Render(errs) +
+		"\n" + wrap.Render(renderPause(stat.congestion))
+	if m.showHist {
+		details += "\n" + renderHist(stat.hist, m.barChars)
+	}
+	return details
+}
+
+// portIDs formats the port's GUID and LID for matching it up with the
+// subnet manager's view of the fabric, leaving out whichever is unknown.
+func portIDs(iface ibmon.Interface) string {
+	var ids []string
+	if iface.GUID != "" {
+		ids = append(ids, "GUID "+iface.GUID)
+	}
+	if iface.LID != 0 {
+		ids = append(ids, fmt.Sprintf("LID %d", iface.LID))
+	}
+	return strings.Join(ids, " ")
+}
+
+// hardware describes the port's adaptor, its model and board ID, leaving
+// out whichever is unknown.
+func hardware(iface ibmon.Interface) string {
+	hw := iface.Model()
+	if iface.BoardID != "" {
+		hw = strings.TrimSpace(hw + " board " + iface.BoardID)
+	}
+	return hw
+}
+
+// renderPause formats the congestion counters for the details block, each
+// with its growth over the last sample, which is highlighted when nonzero.
+func renderPause(counters []ibmon.CounterDelta) string {
+	if len(counters) == 0 {
+		return "pause: n/a"
+	}
+	parts := make([]string, len(counters))
+	for i, c := range counters {
+		delta := fmt.Sprintf("+%d", c.Delta)
+		if c.Delta > 0 {
+			delta = warnStyle.Render(delta)
+		}
+		parts[i] = fmt.Sprintf("%s %d (%s)", c.Name, c.Value, delta)
+	}
+	return "pause: " + strings.Join(parts, " • ")
+}
+
+// rateUnknown reports whether iface has neither a rate file nor a line rate
+// from elsewhere, such as a -replay log.
+func rateUnknown(iface ibmon.Interface) bool {
+	return iface.Rate == "" && iface.MaxGbps == 0
+}
+
+// bottom returns everything drawn below the viewport: the selected port's
+// details, if any, and the footer.
+func (m model) bottom() string {
+	if details := m.renderDetails(); details != "" {
+		return details + "\n" + m.footer()
+	}
+	return m.footer()
+}
+
+// relayout re-renders the content and gives the viewport whatever height the
+// bottom block leaves free.
+func (m *model) relayout() {
+	if m.termHeight > 0 {
+		m.vp.Height = max(1, m.termHeight-lipgloss.Height(m.bottom())-m.pinnedHeight())
+	}
+	m.refresh()
+}
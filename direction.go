@@ -0,0 +1,26 @@
+package main
+
+// Directions the rows can be limited to with -rx-only, -tx-only and the 'v'
+// key. Both directions are always sampled, so totals, logs and sinks are
+// unaffected; only the rows change.
+const (
+	dirBoth = ""   // an RX and a TX bar per row
+	dirRX   = "rx" // only the RX bar, twice as wide
+	dirTX   = "tx" // only the TX bar, twice as wide
+)
+
+// cycleDirection steps the rows from both directions to RX only, TX only
+// and back.
+func (m *model) cycleDirection() {
+	switch m.direction {
+	case dirBoth:
+		m.direction = dirRX
+		m.setNotice("showing RX only")
+	case dirRX:
+		m.direction = dirTX
+		m.setNotice("showing TX only")
+	default:
+		m.direction = dirBoth
+		m.setNotice("showing RX and TX")
+	}
+}
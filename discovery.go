@@ -0,0 +1,64 @@
+package main
+
+import (
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// discoveredMsg delivers the ports found by discoverPorts, or why none were.
+type discoveredMsg struct {
+	statuses []ifaceStatus
+	err      error
+}
+
+// newDiscoveringModel builds a model for the TUI that finds its ports once
+// the program is running, showing a spinner meanwhile, so that the screen is
+// not left blank while a slow sysfs or -wait holds discovery up.
+func newDiscoveringModel(opts options) model {
+	m := newModel(opts)
+	m.discovering = true
+	m.spinner = spinner.New(spinner.WithSpinner(spinner.Dot))
+	m.loadPorts = func() ([]ifaceStatus, error) { return loadStatuses(opts, nil) }
+	return m
+}
+
+// discoverPorts returns a command finding the ports off the UI goroutine.
+func (m model) discoverPorts() tea.Cmd {
+	load := m.loadPorts
+	return func() tea.Msg {
+		statuses, err := load()
+		return discoveredMsg{statuses: statuses, err: err}
+	}
+}
+
+// handleDiscovered takes on the ports found and starts sampling them, with
+// the run timed from now rather than from startup. A failure is shown in
+// place of the ports until the user quits.
+func (m *model) handleDiscovered(msg discoveredMsg) tea.Cmd {
+	m.discovering = false
+	m.loadPorts = nil
+	err := msg.err
+	if err == nil {
+		err = m.setStatuses(msg.statuses)
+	}
+	if err != nil {
+		m.discoverErr = err
+		m.relayout()
+		return nil
+	}
+	now := time.Now()
+	m.started, m.tickAnchor, m.ratesReadAt = now, now, now
+	m.relayout()
+	return tick(m.tickAnchor, m.interval, m.tickGen)
+}
+
+// renderDiscovery is the content while there are no ports to show yet.
+func (m model) renderDiscovery() string {
+	if m.discoverErr != nil {
+		return warnStyle.Render("discovery failed: "+m.discoverErr.Error()) + "\n" +
+			footerStyle.Render("press q to quit") + "\n"
+	}
+	return m.spinner.View() + " discovering interfaces...\n"
+}
@@ -0,0 +1,23 @@
+package main
+
+import "errors"
+
+// emitter is an output fed every snapshot: -json, -socket, -fifo, -grpc,
+// -http, -logfile, -statsd, -otlp and the -bell/-notify alerts. Any
+// combination of them runs at once, alongside the TUI or a headless mode,
+// and each gets every snapshot.
+type emitter interface {
+	emit(snap snapshot) error
+}
+
+// publish fans a snapshot out to every emitter. One that fails does not keep
+// the snapshot from the others; the errors are reported together.
+func (m model) publish(snap snapshot) error {
+	var errs []error
+	for _, e := range m.emitters {
+		if err := e.emit(snap); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
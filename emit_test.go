@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeEmitter records the snapshots it is fed, failing with err if set.
+type fakeEmitter struct {
+	snaps []snapshot
+	err   error
+}
+
+func (f *fakeEmitter) emit(snap snapshot) error {
+	f.snaps = append(f.snaps, snap)
+	return f.err
+}
+
+func TestPublishFansOut(t *testing.T) {
+	first, second := &fakeEmitter{}, &fakeEmitter{}
+	m := model{emitters: []emitter{first, second}}
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		if err := m.publish(snapshot{Time: t0.Add(time.Duration(i) * time.Second)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []*fakeEmitter{first, second} {
+		if len(f.snaps) != 3 || !f.snaps[2].Time.Equal(t0.Add(2*time.Second)) {
+			t.Errorf("emitter got %v, want the 3 snapshots in order", f.snaps)
+		}
+	}
+}
+
+func TestPublishReportsFailures(t *testing.T) {
+	// A failing emitter does not keep the snapshot from the ones after it.
+	errFull := errors.New("disk full")
+	failing, ok := &fakeEmitter{err: errFull}, &fakeEmitter{}
+	m := model{emitters: []emitter{failing, ok}}
+	if err := m.publish(snapshot{}); !errors.Is(err, errFull) {
+		t.Errorf("publish = %v, want %v", err, errFull)
+	}
+	if len(ok.snaps) != 1 {
+		t.Errorf("second emitter got %d snapshots, want 1", len(ok.snaps))
+	}
+
+	if err := (model{}).publish(snapshot{}); err != nil {
+		t.Errorf("publish with no emitters = %v", err)
+	}
+}
+
+func TestRunHeadlessKeepsEmitting(t *testing.T) {
+	// A sink that fails on every snapshot, like a -fifo whose reader went
+	// away, is reported but neither ends the run nor starves the others.
+	failing, ok := &fakeEmitter{err: errors.New("broken pipe")}, &fakeEmitter{}
+	m := model{interval: time.Millisecond, maxTicks: 3, emitters: []emitter{failing, ok}}
+	final, err := runHeadless(m, nil)
+	if err != nil {
+		t.Fatalf("runHeadless = %v", err)
+	}
+	if final.ticks != 3 {
+		t.Errorf("run ended after %d ticks, want 3", final.ticks)
+	}
+	if len(failing.snaps) != 3 || len(ok.snaps) != 3 {
+		t.Errorf("emitters got %d and %d snapshots, want 3 each", len(failing.snaps), len(ok.snaps))
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// fifoRetry is how often fifoSink tries the FIFO again while nothing is
+// reading from it.
+const fifoRetry = 200 * time.Millisecond
+
+// fifoSink streams newline-delimited JSON snapshots into a named pipe that
+// another process reads, for -fifo. Unlike socketServer, ibmon is the
+// writer here: it waits for a reader to open the pipe, writes to it until the
+// reader goes away, and then waits for the next one. Snapshots published
+// while no reader keeps up are dropped rather than stalling the sampler.
+type fifoSink struct {
+	path  string
+	lines chan []byte
+	done  chan struct{}
+}
+
+// newFifoSink starts writing snapshots to the named pipe at path, which must
+// already exist.
+func newFifoSink(path string) (*fifoSink, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.Mode()&os.ModeNamedPipe == 0 {
+		return nil, fmt.Errorf("%s is not a named pipe (create it with mkfifo)", path)
+	}
+	f := &fifoSink{
+		path:  path,
+		lines: make(chan []byte, 4),
+		done:  make(chan struct{}),
+	}
+	go f.run()
+	return f, nil
+}
+
+// run connects to each reader in turn until the sink is closed. Opening a
+// FIFO for writing without blocking fails while there is no reader, so it is
+// retried every fifoRetry until one turns up.
+func (f *fifoSink) run() {
+	for {
+		w, err := os.OpenFile(f.path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+		if err != nil {
+			select {
+			case <-f.done:
+				return
+			case <-time.After(fifoRetry):
+			}
+			continue
+		}
+		f.stream(w)
+		w.Close()
+		select {
+		case <-f.done:
+			return
+		default:
+		}
+	}
+}
+
+// stream writes published snapshots to w until a write fails, typically
+// with EPIPE once the reader has closed its end, or the sink is closed.
+// Snapshots queued before the reader arrived are stale and skipped.
+func (f *fifoSink) stream(w *os.File) {
+	for len(f.lines) > 0 {
+		<-f.lines
+	}
+	for {
+		select {
+		case <-f.done:
+			return
+		case line := <-f.lines:
+			if _, err := w.Write(line); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// emit queues a snapshot for the reader, dropping it if the queue is full
+// because there is no reader or it has fallen behind.
+func (f *fifoSink) emit(snap snapshot) error {
+	line, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	select {
+	case f.lines <- append(line, '\n'):
+	default:
+	}
+	return nil
+}
+
+// Close stops writing; a reader sees end of file.
+func (f *fifoSink) Close() error {
+	close(f.done)
+	return nil
+}
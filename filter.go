@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newFilterInput returns the text input the '/' key opens in the footer.
+func newFilterInput() textinput.Model {
+	ti := textinput.New()
+	ti.Prompt = "/"
+	ti.Placeholder = "filter ports by name"
+	return ti
+}
+
+// filterMatches reports whether stat passes the name filter: its label
+// contains the filter text, or there is none.
+func (m model) filterMatches(stat ifaceStatus) bool {
+	return strings.Contains(stat.name(), m.filter.Value())
+}
+
+// openFilter focuses the filter input, keeping any text already entered.
+func (m *model) openFilter() tea.Cmd {
+	m.filter.CursorEnd()
+	cmd := m.filter.Focus()
+	m.relayout()
+	return cmd
+}
+
+// updateFilter handles a key while the filter input is focused. Every other
+// command key is suspended so it can be typed; enter keeps the filter and
+// returns to the ports, esc clears it. The rows follow the text as it is
+// typed.
+func (m model) updateFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "enter":
+		m.filter.Blur()
+	case "esc":
+		m.filter.Blur()
+		m.filter.Reset()
+	default:
+		m.filter, cmd = m.filter.Update(msg)
+	}
+	m.relayout()
+	return m, cmd
+}
+
+// filterHint returns the footer line shown in place of the key hints while
+// the filter input is focused.
+func (m model) filterHint() string {
+	return m.filter.View() + footerStyle.Render("  (enter keeps • esc clears)")
+}
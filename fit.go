@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// fitGap separates the columns of -fit.
+const fitGap = "  "
+
+// Widths of a -fit cell: the name (10), a space either side of the bar and
+// the two percentages (8), plus the narrowest bar worth a column.
+const (
+	fitFixed       = 20
+	fitMinBarWidth = 4
+)
+
+// fitLines returns how many lines -fit can fill with ports: the screen less
+// the pinned and bottom blocks and tail, the content drawn after the ports.
+func (m model) fitLines(tail string) int {
+	lines := m.termHeight - lipgloss.Height(m.bottom()) - m.pinnedHeight() - strings.Count(tail, "\n")
+	if m.inline {
+		// View's trailing newline.
+		lines--
+	}
+	return max(1, lines)
+}
+
+// renderFit renders the flat view for -fit so that it fits in lines without
+// scrolling: each shown port as a short cell of its name, a thin combined
+// bar and both percentages, in as many columns as that takes, filled top to
+// bottom. Only if the columns would get too narrow for a bar does it run
+// over and scroll.
+//
+//	mlx5_0:1   ███░░░░░░░░░░██  31/ 28%  mlx5_1:1   █░░░░░░░░░░░░░░  12/  0%
+func (m model) renderFit(hostWidth, lines int) string {
+	shown, more := m.shownPorts()
+	if more > 0 {
+		lines = max(1, lines-1)
+	}
+	cols := max(1, (len(shown)+lines-1)/lines)
+	cols = min(cols, max(1, (m.termWidth+len(fitGap))/(hostWidth+fitFixed+fitMinBarWidth+len(fitGap))))
+	rows := (len(shown) + cols - 1) / cols
+	// Spread the ports evenly over the columns the rows call for.
+	cols = max(1, (len(shown)+rows-1)/max(1, rows))
+	cellWidth := (m.termWidth - (cols-1)*len(fitGap)) / cols
+	barWidth := max(fitMinBarWidth, cellWidth-hostWidth-fitFixed)
+
+	var b strings.Builder
+	for r := range rows {
+		var cells []string
+		for c := range cols {
+			if n := c*rows + r; n < len(shown) {
+				cells = append(cells, m.renderFitCell(m.statuses[shown[n]], hostWidth, barWidth, shown[n] == m.selected))
+			}
+		}
+		b.WriteString(strings.Join(cells, fitGap) + "\n")
+	}
+	if more > 0 {
+		b.WriteString(footerStyle.Render(fmt.Sprintf("(… +%d more)", more)) + "\n")
+	}
+	return b.String()
+}
+
+// renderFitCell renders one port's -fit cell with a bar barWidth wide.
+func (m model) renderFitCell(stat ifaceStatus, hostWidth, barWidth int, selected bool) string {
+	var host string
+	if hostWidth > 0 {
+		host = stat.hostName()
+	}
+	name := alignLeft(stat.iface.Adaptor+":"+stat.iface.Port, 10)
+	switch {
+	case selected && m.rowColors:
+		name = rowStyle(stat.name()).Inherit(selectedStyle).Render(name)
+	case selected:
+		name = selectedStyle.Render(name)
+	case m.rowColors:
+		name = rowStyle(stat.name()).Render(name)
+	}
+	name = alignLeft(host, hostWidth) + name
+
+	rest := barWidth + 1 + 8
+	switch {
+	case stat.stale:
+		return name + " " + staleStyle.Render(alignLeft("stale", rest))
+	case stat.paused:
+		return name + " " + staleStyle.Render(alignLeft("paused", rest))
+	}
+	rxValue, txValue := stat.displayValues()
+	rxPct := lineFraction(rxValue, stat.iface.MaxGbps)
+	txPct := lineFraction(txValue, stat.iface.MaxGbps)
+	return fmt.Sprintf("%s %s %3d/%3d%%", name, combinedBar(barWidth, barPosition(rxPct, m.logScale), barPosition(txPct, m.logScale), m.barChars), int(rxPct*100), int(txPct*100))
+}
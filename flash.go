@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// flashFrames is how many ticks -flash highlights a changed rate for. The
+// highlight fades from reverse video to bold on the way out.
+const flashFrames = 2
+
+var (
+	flashStyle     = lipgloss.NewStyle().Bold(true).Reverse(true)
+	flashFadeStyle = lipgloss.NewStyle().Bold(true)
+)
+
+// flash tracks the -flash highlight of one direction's displayed rate.
+type flash struct {
+	shown  float64 // displayed value as of the last tick
+	primed bool    // shown holds a value, so the first tick does not flash
+	frames int     // ticks left to highlight the rate for
+}
+
+// update compares a direction's newly displayed value with the last one,
+// restarting the highlight if it moved by more than threshold Gbps and
+// fading it otherwise.
+func (f *flash) update(value, threshold float64) {
+	switch {
+	case f.primed && math.Abs(value-f.shown) > threshold:
+		f.frames = flashFrames
+	case f.frames > 0:
+		f.frames--
+	}
+	f.shown, f.primed = value, true
+}
+
+// render draws text highlighted for as long as the flash lasts.
+func (f flash) render(text string) string {
+	switch {
+	case f.frames >= flashFrames:
+		return flashStyle.Render(text)
+	case f.frames > 0:
+		return flashFadeStyle.Render(text)
+	}
+	return text
+}
+
+// updateFlashes moves every port's highlights on by a tick. Ports without a
+// new sample keep their values, so their highlights simply fade.
+func (m *model) updateFlashes() {
+	for i := range m.statuses {
+		s := &m.statuses[i]
+		threshold := m.flashPct / 100 * s.iface.MaxGbps
+		rx, tx := s.displayValues()
+		s.rxFlash.update(rx, threshold)
+		s.txFlash.update(tx, threshold)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// getTarget is one counter named by -get, e.g. "mlx5_0:1:rx" or
+// "mlx5_0:1:tx:rate".
+type getTarget struct {
+	adaptor, port string
+	tx            bool // the TX counter rather than RX
+	rate          bool // print the rate over one interval instead of the raw value
+}
+
+// parseGetTarget parses a -get argument of the form adaptor:port:rx|tx with
+// an optional ":rate" suffix.
+func parseGetTarget(s string) (getTarget, error) {
+	fields := strings.Split(s, ":")
+	if len(fields) != 3 && len(fields) != 4 {
+		return getTarget{}, fmt.Errorf("-get %q: want adaptor:port:rx or adaptor:port:tx, optionally followed by :rate", s)
+	}
+	t := getTarget{adaptor: fields[0], port: fields[1]}
+	switch fields[2] {
+	case "rx":
+	case "tx":
+		t.tx = true
+	default:
+		return getTarget{}, fmt.Errorf("-get %q: field %q must be rx or tx", s, fields[2])
+	}
+	if len(fields) == 4 {
+		if fields[3] != "rate" {
+			return getTarget{}, fmt.Errorf("-get %q: unknown suffix %q, want rate", s, fields[3])
+		}
+		t.rate = true
+	}
+	return t, nil
+}
+
+// runGet prints the counter named by t once: its raw value, or with t.rate
+// its rate in the display units over one interval. Only local ports can be
+// read this way.
+func (m model) runGet(w io.Writer, t getTarget) error {
+	var stat *ifaceStatus
+	var names []string
+	for i := range m.statuses {
+		s := &m.statuses[i]
+		if s.host != nil {
+			return fmt.Errorf("-get cannot read remote ports")
+		}
+		names = append(names, s.name())
+		if s.iface.Adaptor == t.adaptor && s.iface.Port == t.port {
+			stat = s
+		}
+	}
+	if stat == nil {
+		return fmt.Errorf("-get: no interface %s:%s (found %s)", t.adaptor, t.port, strings.Join(names, ", "))
+	}
+
+	if !t.rate {
+		rx, tx, err := stat.iface.ReadCounters()
+		if err != nil {
+			return err
+		}
+		value := rx
+		if t.tx {
+			value = tx
+		}
+		_, err = fmt.Fprintln(w, value)
+		return err
+	}
+
+	// Measure from now rather than from discovery.
+	if err := stat.iface.Rebase(); err != nil {
+		return err
+	}
+	time.Sleep(m.interval)
+	tp, err := stat.iface.Sample(m.interval)
+	if err != nil {
+		return err
+	}
+	value := tp.RxGbps
+	if t.tx {
+		value = tp.TxGbps
+	}
+	_, err = fmt.Fprintf(w, "%.*f\n", m.precision, displayUnits(value, m.base2))
+	return err
+}
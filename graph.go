@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	chart "github.com/wcharczuk/go-chart/v2"
+)
+
+// defaultGraphSpan is how much history -graph keeps per interface.
+const defaultGraphSpan = time.Hour
+
+// Size of each chart written by -graph; with one chart per interface they
+// are stacked vertically.
+const (
+	graphWidth  = 1200
+	graphHeight = 360
+)
+
+// writeGraph renders the recorded -graph history as a time-series chart of
+// RX and TX per interface, to a PNG or SVG file chosen by path's extension.
+// With overlay every interface shares one chart; otherwise each gets its own.
+func (m model) writeGraph(path string, overlay bool) error {
+	svg := strings.EqualFold(filepath.Ext(path), ".svg")
+
+	var charts []chart.Chart
+	if overlay {
+		c := m.newGraphChart("ibmon throughput")
+		for _, stat := range m.statuses {
+			c.Series = append(c.Series, m.graphSeries(stat, stat.name()+" ")...)
+		}
+		charts = append(charts, c)
+	} else {
+		for _, stat := range m.statuses {
+			c := m.newGraphChart(stat.name())
+			c.Series = m.graphSeries(stat, "")
+			charts = append(charts, c)
+		}
+	}
+	for i := range charts {
+		if len(charts[i].Series) == 0 {
+			return fmt.Errorf("-graph: not enough samples to plot")
+		}
+		// Start the Y axis at zero, and give it some height even when
+		// every sample is idle.
+		top := 1.0
+		for _, series := range charts[i].Series {
+			top = max(top, slices.Max(series.(chart.TimeSeries).YValues))
+		}
+		charts[i].YAxis.Range = &chart.ContinuousRange{Min: 0, Max: top * 1.05}
+		charts[i].Elements = []chart.Renderable{chart.Legend(&charts[i])}
+	}
+
+	var out []byte
+	var err error
+	if svg {
+		out, err = stackSVG(charts)
+	} else {
+		out, err = stackPNG(charts)
+	}
+	if err != nil {
+		return fmt.Errorf("-graph: %w", err)
+	}
+	return os.WriteFile(path, out, 0o644)
+}
+
+// newGraphChart returns an empty chart with time on the X axis and the
+// display unit on the Y axis.
+func (m model) newGraphChart(title string) chart.Chart {
+	unit := "Gbit/s"
+	switch {
+	case m.packets:
+		unit = "Gpkt/s"
+	case m.base2:
+		unit = "Gibit/s"
+	}
+	return chart.Chart{
+		Title:  title,
+		Width:  graphWidth,
+		Height: graphHeight,
+		Background: chart.Style{
+			Padding: chart.Box{Top: 40, Left: 20, Right: 20, Bottom: 20},
+		},
+		XAxis: chart.XAxis{ValueFormatter: chart.TimeValueFormatterWithFormat("15:04:05")},
+		YAxis: chart.YAxis{Name: unit},
+	}
+}
+
+// graphSeries returns the RX and TX series for one interface, prefixing
+// their names with label. An interface with fewer than two samples has
+// nothing to draw.
+func (m model) graphSeries(stat ifaceStatus, label string) []chart.Series {
+	samples := stat.history.samples
+	if len(samples) < 2 {
+		return nil
+	}
+	times := make([]time.Time, len(samples))
+	rx := make([]float64, len(samples))
+	tx := make([]float64, len(samples))
+	for i, s := range samples {
+		times[i] = s.at
+		rx[i] = displayUnits(s.rx, m.base2)
+		tx[i] = displayUnits(s.tx, m.base2)
+	}
+	return []chart.Series{
+		chart.TimeSeries{Name: label + "RX", XValues: times, YValues: rx},
+		chart.TimeSeries{Name: label + "TX", XValues: times, YValues: tx},
+	}
+}
+
+// stackPNG renders the charts and stacks them into one PNG.
+func stackPNG(charts []chart.Chart) ([]byte, error) {
+	canvas := image.NewRGBA(image.Rect(0, 0, graphWidth, graphHeight*len(charts)))
+	for i, c := range charts {
+		var buf bytes.Buffer
+		if err := c.Render(chart.PNG, &buf); err != nil {
+			return nil, err
+		}
+		img, err := png.Decode(&buf)
+		if err != nil {
+			return nil, err
+		}
+		draw.Draw(canvas, img.Bounds().Add(image.Pt(0, i*graphHeight)), img, image.Point{}, draw.Src)
+	}
+	var out bytes.Buffer
+	err := png.Encode(&out, canvas)
+	return out.Bytes(), err
+}
+
+// stackSVG renders the charts and nests them, offset vertically, in one SVG.
+func stackSVG(charts []chart.Chart) ([]byte, error) {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`+"\n", graphWidth, graphHeight*len(charts))
+	for i, c := range charts {
+		var buf bytes.Buffer
+		if err := c.Render(chart.SVG, &buf); err != nil {
+			return nil, err
+		}
+		out.WriteString(strings.Replace(buf.String(), "<svg ", fmt.Sprintf(`<svg y="%d" `, i*graphHeight), 1))
+	}
+	out.WriteString("</svg>\n")
+	return out.Bytes(), nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// selectedStyle highlights the header of the row under the cursor.
+var selectedStyle = lipgloss.NewStyle().Reverse(true)
+
+// adaptorGroup is one adaptor's ports, shown as a single collapsible row in
+// the grouped view.
+type adaptorGroup struct {
+	key     string // unique across hosts: "[host ]adaptor"
+	adaptor string
+	members []ifaceStatus
+}
+
+// adaptorGroups returns the statuses grouped by adaptor, in discovery order.
+func (m model) adaptorGroups() []adaptorGroup {
+	var groups []adaptorGroup
+	index := make(map[string]int)
+	for _, stat := range m.statuses {
+		key := stat.iface.Adaptor
+		if host := stat.hostName(); host != "" {
+			key = host + " " + key
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, adaptorGroup{key: key, adaptor: stat.iface.Adaptor})
+		}
+		groups[i].members = append(groups[i].members, stat)
+	}
+	return groups
+}
+
+// sumStatuses combines several ports into one synthetic status whose line
+// rate, displayed throughput, averages and totals are the sums of its
+// members'. Paused members are left out, and the result is stale only if
+// every member that is not paused is.
+func sumStatuses(members []ifaceStatus) ifaceStatus {
+	var sum ifaceStatus
+	sum.stale = slices.ContainsFunc(members, func(s ifaceStatus) bool { return !s.paused })
+	for _, stat := range members {
+		if stat.paused {
+			continue
+		}
+		rx, tx := stat.displayValues()
+		sum.iface.MaxGbps += stat.iface.MaxGbps
+		sum.rxValue += rx
+		sum.txValue += tx
+		sum.rxAvg += stat.rxAvg
+		sum.txAvg += stat.txAvg
+		sum.rxTotal += stat.rxTotal
+		sum.txTotal += stat.txTotal
+		sum.stale = sum.stale && stat.stale
+		if sum.host == nil {
+			sum.host = stat.host
+		}
+	}
+	return sum
+}
+
+// renderGroups renders the grouped view: one summary row per adaptor, with
+// the port rows of expanded adaptors indented beneath it. It also returns
+// the line on which the cursor's row starts.
+func (m model) renderGroups() (string, int) {
+	var b strings.Builder
+	hostWidth := m.hostWidth()
+	cursorLine := 0
+	for i, g := range m.adaptorGroups() {
+		marker := "+"
+		if m.expanded[g.key] {
+			marker = "-"
+		}
+		if i == m.groupCursor {
+			cursorLine = strings.Count(b.String(), "\n")
+		}
+		b.WriteString(m.renderRow(marker+" "+g.adaptor, sumStatuses(g.members), hostWidth, i == m.groupCursor) + "\n")
+		if !m.expanded[g.key] {
+			continue
+		}
+		for _, stat := range g.members {
+			if m.hideIdle && stat.idle() {
+				continue
+			}
+			b.WriteString(m.renderRow("  :"+stat.iface.Port, stat, hostWidth, false) + "\n")
+		}
+	}
+	return b.String(), cursorLine
+}
+
+// moveGroupCursor moves the grouped-view cursor by delta adaptors, keeping it
+// in range and scrolling the viewport so its row stays visible.
+func (m *model) moveGroupCursor(delta int) {
+	n := len(m.adaptorGroups())
+	m.groupCursor = max(0, min(n-1, m.groupCursor+delta))
+	m.refresh()
+	_, line := m.renderGroups()
+	m.scrollTo(line)
+}
+
+// toggleGroup expands or collapses the adaptor under the cursor.
+func (m *model) toggleGroup() {
+	groups := m.adaptorGroups()
+	if m.groupCursor >= len(groups) {
+		return
+	}
+	key := groups[m.groupCursor].key
+	m.expanded[key] = !m.expanded[key]
+	m.refresh()
+}
+
+// scrollTo adjusts the viewport offset, if needed, so line is visible.
+func (m *model) scrollTo(line int) {
+	if line < m.vp.YOffset {
+		m.vp.SetYOffset(line)
+	} else if line >= m.vp.YOffset+m.vp.Height {
+		m.vp.SetYOffset(line - m.vp.Height + 1)
+	}
+}
@@ -0,0 +1,98 @@
+//go:build grpc
+
+package main
+
+import (
+	"net"
+	"sync"
+
+	"github.com/apsu/ibmon/ibmonpb"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcServer serves the ibmonpb Monitor service, streaming every published
+// snapshot to each Subscribe call as one Sample per port.
+type grpcServer struct {
+	ibmonpb.UnimplementedMonitorServer
+
+	srv *grpc.Server
+	mu  sync.Mutex
+	// subscribers maps each open stream to its queue of pending samples.
+	subscribers map[chan []*ibmonpb.Sample]struct{}
+}
+
+// newGRPCServer listens on addr ("host:port" or ":port", plaintext) and
+// starts serving in the background.
+func newGRPCServer(addr string) (snapshotServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &grpcServer{
+		srv:         grpc.NewServer(),
+		subscribers: make(map[chan []*ibmonpb.Sample]struct{}),
+	}
+	ibmonpb.RegisterMonitorServer(s.srv, s)
+	go s.srv.Serve(ln)
+	return s, nil
+}
+
+// Subscribe streams samples to one client until it cancels or a send fails.
+func (s *grpcServer) Subscribe(_ *ibmonpb.SubscribeRequest, stream grpc.ServerStreamingServer[ibmonpb.Sample]) error {
+	ch := make(chan []*ibmonpb.Sample, 4)
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case samples := <-ch:
+			for _, sample := range samples {
+				if err := stream.Send(sample); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// emit queues a snapshot for every subscriber. A subscriber that has
+// fallen behind misses the snapshot rather than stalling the sampler.
+func (s *grpcServer) emit(snap snapshot) error {
+	samples := make([]*ibmonpb.Sample, 0, len(snap.Interfaces))
+	at := timestamppb.New(snap.Time)
+	for _, iface := range snap.Interfaces {
+		samples = append(samples, &ibmonpb.Sample{
+			Adaptor:   iface.Adaptor,
+			Port:      iface.Port,
+			RxGbps:    iface.RxGbps,
+			TxGbps:    iface.TxGbps,
+			Time:      at,
+			ReadError: iface.ReadError,
+		})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subscribers {
+		select {
+		case ch <- samples:
+		default:
+		}
+	}
+	return nil
+}
+
+// Close ends every open stream and stops the server.
+func (s *grpcServer) Close() error {
+	s.srv.Stop()
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !grpc
+
+package main
+
+import "errors"
+
+// newGRPCServer reports that the gRPC service was left out of this build;
+// build with -tags grpc to include it.
+func newGRPCServer(addr string) (snapshotServer, error) {
+	return nil, errors.New("-grpc: ibmon was built without gRPC support (rebuild with -tags grpc)")
+}
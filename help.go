@@ -0,0 +1,158 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// helpKey is one line of the help overlay: a key, what it does and, for a
+// toggle or setting, a function returning its current state.
+type helpKey struct {
+	key, desc string
+	state     func(m model) string
+}
+
+// onOff returns the state of a toggle for the help overlay.
+func onOff(on func(m model) bool) func(model) string {
+	return func(m model) string {
+		if on(m) {
+			return "on"
+		}
+		return "off"
+	}
+}
+
+// helpSections lists every key by category, in the order the help overlay
+// shows them. A key added to Update belongs here too.
+var helpSections = []struct {
+	title string
+	keys  []helpKey
+}{
+	{"Navigation", []helpKey{
+		{"↑/↓ k/j", "select a port", nil},
+		{"esc", "clear the selection", nil},
+		{"wheel pgup/pgdn", "scroll", nil},
+		{"home/end", "jump to the top/bottom", nil},
+		{"/", "filter ports", func(m model) string { return m.filter.Value() }},
+		{"enter", "expand a group", nil},
+	}},
+	{"View", []helpKey{
+		{"h", "hide idle ports", onOff(func(m model) bool { return m.hideIdle })},
+		{"T", "top busiest ports", func(m model) string {
+			if m.top == 0 {
+				return "all"
+			}
+			return fmt.Sprint(m.top)
+		}},
+		{"v", "rx/tx only", func(m model) string { return cmp.Or(m.direction, "both") }},
+		{"s", "speeds", onOff(func(m model) bool { return m.showSpeed })},
+		{"a", "rx:tx balance", onOff(func(m model) bool { return m.showRatio })},
+		{"o", "average marker", onOff(func(m model) bool { return m.showAvgMarker })},
+		{"b", "baseline deltas", onOff(func(m model) bool { return m.baseline != nil })},
+		{"L", "column labels", onOff(func(m model) bool { return m.showHeaders })},
+		{"c", "totals", onOff(func(m model) bool { return m.showTotals })},
+		{"x", "raw counters", onOff(func(m model) bool { return m.raw })},
+		{"H", "histogram", onOff(func(m model) bool { return m.showHist })},
+		{"d", "temperatures", onOff(func(m model) bool { return m.showDiag })},
+		{"G", "group by adaptor", onOff(func(m model) bool { return m.grouped })},
+		{"m", "group members", onOff(func(m model) bool { return m.showMembers })},
+	}},
+	{"Actions", []helpKey{
+		{"+/-", "faster/slower", func(m model) string { return m.interval.String() }},
+		{"p", "pause the selected port", nil},
+		{"r", "reset totals", nil},
+		{"R", "reset all", nil},
+		{"y", "copy a snapshot", nil},
+		{"?", "this help", nil},
+		{"q", "quit", nil},
+	}},
+}
+
+var (
+	helpPanelStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("#5A56E0")).Padding(0, 1)
+	helpTitleStyle = lipgloss.NewStyle().Bold(true)
+	helpKeyStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#EE6FF8"))
+	helpOnStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#5FD75F")).Bold(true)
+	dimStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("#4E4E4E"))
+)
+
+// helpHints is the footer's short list of keys; '?' shows the rest.
+const helpHints = "↑/↓ select • / filter • h hide idle • G group • +/- interval • ? all keys • q quit"
+
+// renderHelpSection renders one category of the help overlay, with the keys
+// and descriptions in columns and each state after its description.
+func (m model) renderHelpSection(title string, keys []helpKey) string {
+	keyWidth, descWidth := 0, 0
+	for _, k := range keys {
+		keyWidth = max(keyWidth, lipgloss.Width(k.key))
+		descWidth = max(descWidth, len(k.desc))
+	}
+	lines := []string{helpTitleStyle.Render(title)}
+	for _, k := range keys {
+		line := helpKeyStyle.Render(alignLeft(k.key, keyWidth)) + "  " + alignLeft(k.desc, descWidth)
+		if k.state != nil {
+			state := k.state(m)
+			if state == "on" {
+				state = helpOnStyle.Render(state)
+			}
+			line += "  " + state
+		}
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// helpGap separates the columns of the help panel.
+const helpGap = "    "
+
+// renderHelp renders the help panel: the categories side by side in as
+// many columns as the terminal has room for, the long list of view toggles
+// getting a column of its own before the others share one.
+func (m model) renderHelp() string {
+	sections := make([]string, len(helpSections))
+	for i, s := range helpSections {
+		sections[i] = m.renderHelpSection(s.title, s.keys)
+	}
+	nav, view, actions := sections[0], sections[1], sections[2]
+	frame := helpPanelStyle.GetHorizontalFrameSize()
+	var body string
+	for _, layout := range []string{
+		lipgloss.JoinHorizontal(lipgloss.Top, nav, helpGap, view, helpGap, actions),
+		lipgloss.JoinHorizontal(lipgloss.Top, nav+"\n\n"+actions, helpGap, view),
+		strings.Join(sections, "\n\n"),
+	} {
+		body = layout
+		if lipgloss.Width(body)+frame <= m.termWidth {
+			break
+		}
+	}
+	title := helpTitleStyle.Render("Keys") + footerStyle.Render(" ('?' or esc closes)")
+	return helpPanelStyle.Render(title + "\n\n" + body)
+}
+
+// overlay draws panel centered over background, which is dimmed and
+// stripped of its own styling so the panel stands out.
+func overlay(background, panel string, width int) string {
+	bg := strings.Split(stripANSI(background), "\n")
+	fg := strings.Split(panel, "\n")
+	panelWidth := lipgloss.Width(panel)
+	x := max(0, (width-panelWidth)/2)
+	y := max(0, (len(bg)-len(fg))/2)
+	for i, line := range bg {
+		if i < y || i >= y+len(fg) {
+			bg[i] = dimStyle.Render(line)
+			continue
+		}
+		cells := []rune(line)
+		left := string(cells[:min(x, len(cells))])
+		right := ""
+		if x+panelWidth < len(cells) {
+			right = string(cells[x+panelWidth:])
+		}
+		bg[i] = dimStyle.Render(alignLeft(left, x)) + lipgloss.PlaceHorizontal(panelWidth, lipgloss.Left, fg[i-y]) + dimStyle.Render(right)
+	}
+	return strings.Join(bg, "\n")
+}
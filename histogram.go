@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// histBuckets is the number of utilization buckets, each histBuckets-th of
+// line rate wide: 0–10%, 10–20%, ... 90–100%.
+const histBuckets = 10
+
+// utilHist counts samples by utilization of line rate, per direction.
+type utilHist struct {
+	rx, tx [histBuckets]int
+	n      int // samples counted
+}
+
+// add counts one sample. Ports with no known line rate are not counted.
+func (h *utilHist) add(rx, tx, maxGbps float64) {
+	if maxGbps <= 0 {
+		return
+	}
+	h.rx[histBucket(lineFraction(rx, maxGbps))]++
+	h.tx[histBucket(lineFraction(tx, maxGbps))]++
+	h.n++
+}
+
+// histBucket returns the bucket for a fraction of line rate; a saturated
+// link falls in the top bucket.
+func histBucket(frac float64) int {
+	return min(histBuckets-1, int(frac*histBuckets))
+}
+
+// share returns the percentage of samples in each bucket of counts.
+func (h utilHist) share(counts [histBuckets]int) [histBuckets]int {
+	var pct [histBuckets]int
+	if h.n == 0 {
+		return pct
+	}
+	for i, c := range counts {
+		pct[i] = int(math.Round(float64(c) * 100 / float64(h.n)))
+	}
+	return pct
+}
+
+// histLabel names bucket i, e.g. "10–20%".
+func histLabel(i int) string {
+	step := 100 / histBuckets
+	return fmt.Sprintf("%d–%d%%", i*step, (i+1)*step)
+}
+
+// histBarWidth is the width of the longest bar drawn by renderHist.
+const histBarWidth = 20
+
+// renderHist draws the selected port's utilization histogram for the 'H'
+// detail view: one line per bucket with an RX and a TX bar, each scaled to
+// the share of samples in the bucket, drawn with chars.
+func renderHist(h utilHist, chars barChars) string {
+	if h.n == 0 {
+		return "utilization: no samples against a known line rate yet"
+	}
+	rxPct, txPct := h.share(h.rx), h.share(h.tx)
+	bar := func(pct int) string {
+		filled := pct * histBarWidth / 100
+		full, empty := chars.fill(filled, histBarWidth-filled)
+		return full + emptyBarStyle.Render(empty)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "utilization over %d samples:", h.n)
+	for i := histBuckets - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "\n%8s %s %s %3d%%  %s %s %3d%%",
+			histLabel(i), rxArrow(), rxBarStyle.Render(bar(rxPct[i])), rxPct[i],
+			txArrow(), txBarStyle.Render(bar(txPct[i])), txPct[i])
+	}
+	return b.String()
+}
+
+// writeHistograms prints, after a blank line, every port's utilization
+// histogram as a table of the percentage of samples per bucket, one row per
+// port and direction. Nothing is printed if no port has a histogram.
+func (m model) writeHistograms(w io.Writer) error {
+	nameWidth, found := len("UTILIZATION"), false
+	for _, stat := range m.statuses {
+		nameWidth = max(nameWidth, len(stat.name())+len(" RX"))
+		found = found || stat.hist.n > 0
+	}
+	if !found {
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n%-*s", nameWidth, "UTILIZATION")
+	for i := range histBuckets {
+		fmt.Fprintf(&b, " %7s", histLabel(i))
+	}
+	b.WriteString("  (% of samples)\n")
+	for _, stat := range m.statuses {
+		if stat.hist.n == 0 {
+			continue
+		}
+		for _, dir := range []struct {
+			name   string
+			counts [histBuckets]int
+		}{{"RX", stat.hist.rx}, {"TX", stat.hist.tx}} {
+			fmt.Fprintf(&b, "%-*s", nameWidth, stat.name()+" "+dir.name)
+			for _, pct := range stat.hist.share(dir.counts) {
+				fmt.Fprintf(&b, " %7d", pct)
+			}
+			b.WriteString("\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
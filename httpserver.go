@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// httpServer serves the latest snapshot as a table over plain HTTP, for
+// -http: a web view for browsers and for network management systems that
+// scrape text tables, with nothing to deploy.
+//
+//	/     throughput per port, as HTML for browsers and plain text otherwise
+//	/raw  the byte and wrap counts behind it, as plain text
+type httpServer struct {
+	srv *http.Server
+
+	mu   sync.Mutex
+	snap snapshot // the latest snapshot, updated every tick
+}
+
+// newHTTPServer listens on addr and starts serving in the background.
+func newHTTPServer(addr string) (*httpServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &httpServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.serveTable)
+	mux.HandleFunc("GET /raw", s.serveRaw)
+	s.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go s.srv.Serve(ln)
+	return s, nil
+}
+
+// emit makes snap the snapshot served from now on.
+func (s *httpServer) emit(snap snapshot) error {
+	s.mu.Lock()
+	s.snap = snap
+	s.mu.Unlock()
+	return nil
+}
+
+// latest returns the snapshot to serve.
+func (s *httpServer) latest() snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snap
+}
+
+// serveTable serves the current throughput of every port.
+func (s *httpServer) serveTable(w http.ResponseWriter, r *http.Request) {
+	snap := s.latest()
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		writeHTMLTable(w, snap)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "# %s\n", snap.Time.Format(time.RFC3339))
+	fmt.Fprintln(tw, "ADAPTOR\tPORT\tRATE\tRX GBPS\tTX GBPS\tSTALE")
+	for _, p := range snap.Interfaces {
+		fmt.Fprintf(tw, "%s\t%s\t%.0fG\t%.3f\t%.3f\t%t\n", p.portHost(), p.Port, p.MaxGbps, p.RxGbps, p.TxGbps, p.Stale)
+	}
+	tw.Flush()
+}
+
+// portHost returns the adaptor column of the tables: the adaptor, prefixed
+// with the host for -remote.
+func (p ifaceSnapshot) portHost() string {
+	if p.Host != "" {
+		return p.Host + ":" + p.Adaptor
+	}
+	return p.Adaptor
+}
+
+// writeHTMLTable writes snap as a bare HTML page that reloads itself.
+func writeHTMLTable(w io.Writer, snap snapshot) {
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html><head><meta http-equiv=\"refresh\" content=\"5\"><title>ibmon</title></head><body>\n")
+	fmt.Fprintf(w, "<p>%s</p>\n<table border=\"1\" cellpadding=\"4\">\n", html.EscapeString(snap.Time.Format(time.RFC3339)))
+	fmt.Fprintf(w, "<tr><th>Adaptor</th><th>Port</th><th>Rate</th><th>RX Gbps</th><th>TX Gbps</th></tr>\n")
+	for _, p := range snap.Interfaces {
+		rx, tx := fmt.Sprintf("%.3f", p.RxGbps), fmt.Sprintf("%.3f", p.TxGbps)
+		if p.Stale {
+			rx, tx = "stale", "stale"
+		}
+		fmt.Fprintf(w, "<tr><td>%s</td><td>%s</td><td>%.0fG</td><td>%s</td><td>%s</td></tr>\n",
+			html.EscapeString(p.portHost()), html.EscapeString(p.Port), p.MaxGbps, rx, tx)
+	}
+	fmt.Fprintf(w, "</table>\n</body></html>\n")
+}
+
+// serveRaw serves the counts behind the rates: bytes moved in each
+// direction and counter wraps, since start or the last reset.
+func (s *httpServer) serveRaw(w http.ResponseWriter, _ *http.Request) {
+	snap := s.latest()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "# %s\n", snap.Time.Format(time.RFC3339))
+	fmt.Fprintln(tw, "ADAPTOR\tPORT\tRX BYTES\tTX BYTES\tWRAPS")
+	for _, p := range snap.Interfaces {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\n", p.portHost(), p.Port, p.RxBytes, p.TxBytes, p.Wraps)
+	}
+	tw.Flush()
+}
+
+// Close stops serving.
+func (s *httpServer) Close() error {
+	return s.srv.Close()
+}
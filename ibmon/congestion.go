@@ -0,0 +1,76 @@
+package ibmon
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// congestionCounters lists the counters read by SampleCongestion, by
+// directory under the port. port_xmit_wait counts ticks in which the port
+// had data to send but was held back by flow control, so its growth shows
+// congestion even while the data rate looks healthy. The hw_counters are the
+// RoCE congestion notifications of mlx5: ECN-marked packets received, and
+// CNPs sent and handled. PFC pause frames themselves are only counted by the
+// Ethernet driver (ethtool), not under the RDMA device.
+var congestionCounters = []struct{ dir, name string }{
+	{CountersStd, "port_xmit_wait"},
+	{CountersHW, "np_ecn_marked_roce_packets"},
+	{CountersHW, "np_cnp_sent"},
+	{CountersHW, "rp_cnp_handled"},
+}
+
+// CounterDelta is a counter value and its change since the previous reading.
+type CounterDelta struct {
+	Name  string
+	Value int64
+	Delta int64
+}
+
+// SampleCongestion reads the port's congestion counters and returns each
+// with its change since the previous SampleCongestion, or zero change on the
+// first. Counters the driver does not expose are left out, so on fabrics with
+// none the result is empty. It fails with os.ErrNotExist for interfaces built
+// with NewInterface.
+func (i *Interface) SampleCongestion() ([]CounterDelta, error) {
+	counters, err := i.ReadCongestion()
+	if err != nil {
+		return nil, err
+	}
+	return i.RecordCongestion(counters), nil
+}
+
+// ReadCongestion reads the port's congestion counters as SampleCongestion
+// does, but changes nothing; RecordCongestion works out their changes. Like
+// ReadPair, it can be called for many ports at once.
+func (i *Interface) ReadCongestion() ([]Counter, error) {
+	if i.portPath == "" {
+		return nil, os.ErrNotExist
+	}
+	var counters []Counter
+	for _, c := range congestionCounters {
+		v, err := readCounter(filepath.Join(i.portPath, c.dir, c.name))
+		if err != nil {
+			continue
+		}
+		counters = append(counters, Counter{Name: c.name, Value: v})
+	}
+	return counters, nil
+}
+
+// RecordCongestion returns counters, read by ReadCongestion, each with its
+// change since the previous reading, and keeps them for the next.
+func (i *Interface) RecordCongestion(counters []Counter) []CounterDelta {
+	if i.prevCongestion == nil {
+		i.prevCongestion = make(map[string]int64)
+	}
+	var deltas []CounterDelta
+	for _, c := range counters {
+		d := CounterDelta{Name: c.Name, Value: c.Value}
+		if prev, ok := i.prevCongestion[c.Name]; ok {
+			d.Delta, _, _ = counterDelta(prev, c.Value)
+		}
+		i.prevCongestion[c.Name] = c.Value
+		deltas = append(deltas, d)
+	}
+	return deltas
+}
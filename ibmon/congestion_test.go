@@ -0,0 +1,39 @@
+package ibmon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSampleCongestion(t *testing.T) {
+	root := t.TempDir()
+	fakePort(t, root, "mlx5_0", "1", CountersStd, "port_rcv_data", "port_xmit_data")(0, 0)
+	ifaces, err := Discover(Options{SysfsPath: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	iface := &ifaces[0]
+
+	// A fabric without congestion counters yields none, not an error.
+	if got, err := iface.SampleCongestion(); err != nil || len(got) != 0 {
+		t.Fatalf("SampleCongestion without counters = %v, %v; want none", got, err)
+	}
+
+	wait := filepath.Join(root, "mlx5_0", "ports", "1", CountersStd, "port_xmit_wait")
+	for n, step := range []struct {
+		value string
+		want  CounterDelta
+	}{
+		{value: "100\n", want: CounterDelta{Name: "port_xmit_wait", Value: 100}},
+		{value: "160\n", want: CounterDelta{Name: "port_xmit_wait", Value: 160, Delta: 60}},
+	} {
+		if err := os.WriteFile(wait, []byte(step.value), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := iface.SampleCongestion()
+		if err != nil || len(got) != 1 || got[0] != step.want {
+			t.Errorf("step %d: SampleCongestion = %+v, %v; want [%+v]", n, got, err, step.want)
+		}
+	}
+}
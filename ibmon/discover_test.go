@@ -0,0 +1,76 @@
+package ibmon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiscoverPortIDs(t *testing.T) {
+	root := t.TempDir()
+	fakePort(t, root, "mlx5_0", "1", CountersStd, "port_rcv_data", "port_xmit_data")(0, 0)
+	fakePort(t, root, "mlx5_1", "1", CountersStd, "port_rcv_data", "port_xmit_data")(0, 0)
+	portPath := filepath.Join(root, "mlx5_0", "ports", "1")
+	if err := os.MkdirAll(filepath.Join(portPath, "gids"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"gids/0": "fe80:0000:0000:0000:0002:c903:00a1:b2c3\n",
+		"lid":    "0x2a\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(portPath, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ifaces, err := Discover(Options{SysfsPath: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ifaces) != 2 {
+		t.Fatalf("Discover found %d interfaces, want 2", len(ifaces))
+	}
+	if got := ifaces[0]; got.GUID != "0x0002c90300a1b2c3" || got.LID != 42 {
+		t.Errorf("mlx5_0 GUID, LID = %q, %d; want 0x0002c90300a1b2c3, 42", got.GUID, got.LID)
+	}
+	// Without the files, as on RoCE, both stay unknown.
+	if got := ifaces[1]; got.GUID != "" || got.LID != 0 {
+		t.Errorf("mlx5_1 GUID, LID = %q, %d; want empty", got.GUID, got.LID)
+	}
+}
+
+func TestDiscoverModel(t *testing.T) {
+	root := t.TempDir()
+	fakePort(t, root, "mlx5_0", "1", CountersStd, "port_rcv_data", "port_xmit_data")(0, 0)
+	fakePort(t, root, "mlx5_1", "1", CountersStd, "port_rcv_data", "port_xmit_data")(0, 0)
+	fakePort(t, root, "mlx5_2", "1", CountersStd, "port_rcv_data", "port_xmit_data")(0, 0)
+	files := map[string]string{
+		"mlx5_0/hca_type": "MT4125\n",
+		"mlx5_0/board_id": "MT_0000000359\n",
+		"mlx5_1/hca_type": "MT9999\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ifaces, err := Discover(Options{SysfsPath: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ model, boardID string }{
+		{"MT4125 ConnectX-6 Dx", "MT_0000000359"},
+		{"MT9999", ""}, // a device ID ibmon has no name for
+		{"", ""},       // no attributes at all
+	}
+	if len(ifaces) != len(want) {
+		t.Fatalf("Discover found %d interfaces, want %d", len(ifaces), len(want))
+	}
+	for i, w := range want {
+		if got := ifaces[i]; got.Model() != w.model || got.BoardID != w.boardID {
+			t.Errorf("%s Model, BoardID = %q, %q; want %q, %q", got.Adaptor, got.Model(), got.BoardID, w.model, w.boardID)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package ibmon
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ErrorCounterNames lists the per-port error counters read by ErrorCounters,
+// as named under ports/<n>/counters in sysfs.
+var ErrorCounterNames = []string{
+	"symbol_error",
+	"link_error_recovery",
+	"link_downed",
+	"port_rcv_errors",
+	"port_rcv_remote_physical_errors",
+	"port_rcv_switch_relay_errors",
+	"port_xmit_discards",
+	"port_xmit_constraint_errors",
+	"port_rcv_constraint_errors",
+	"local_link_integrity_errors",
+	"excessive_buffer_overrun_errors",
+	"VL15_dropped",
+}
+
+// Counter is a named counter value.
+type Counter struct {
+	Name  string
+	Value int64
+}
+
+// ErrorCounters reads the port's error counters, in ErrorCounterNames order.
+// Counters the driver does not expose are left out. It fails with
+// os.ErrNotExist for interfaces built with NewInterface.
+func (i *Interface) ErrorCounters() ([]Counter, error) {
+	if i.portPath == "" {
+		return nil, os.ErrNotExist
+	}
+	dir := filepath.Join(i.portPath, CountersStd)
+	var counters []Counter
+	for _, name := range ErrorCounterNames {
+		v, err := readCounter(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		counters = append(counters, Counter{Name: name, Value: v})
+	}
+	return counters, nil
+}
@@ -0,0 +1,52 @@
+package ibmon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hcaFamilies names the products behind the device IDs mlx4 and mlx5 report
+// in hca_type.
+var hcaFamilies = map[string]string{
+	"MT4099":  "ConnectX-3",
+	"MT4103":  "ConnectX-3 Pro",
+	"MT4115":  "ConnectX-4",
+	"MT4117":  "ConnectX-4 Lx",
+	"MT4119":  "ConnectX-5",
+	"MT4121":  "ConnectX-5 Ex",
+	"MT4123":  "ConnectX-6",
+	"MT4125":  "ConnectX-6 Dx",
+	"MT4127":  "ConnectX-6 Lx",
+	"MT4129":  "ConnectX-7",
+	"MT4131":  "ConnectX-8",
+	"MT41682": "BlueField",
+	"MT41686": "BlueField-2",
+	"MT41692": "BlueField-3",
+}
+
+// readAttr returns the trimmed content of a sysfs attribute of the adaptor,
+// or "" if it cannot be read.
+func readAttr(adaptorPath, name string) string {
+	data, err := os.ReadFile(filepath.Join(adaptorPath, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Family returns the product name of the adaptor, e.g. "ConnectX-6 Dx",
+// or "" if its HCAType is not one ibmon knows.
+func (i Interface) Family() string {
+	return hcaFamilies[i.HCAType]
+}
+
+// Model describes the adaptor's hardware, e.g. "MT4125 ConnectX-6 Dx", or
+// just its HCAType if the family is unknown. It is empty if the driver does
+// not report hca_type.
+func (i Interface) Model() string {
+	if family := i.Family(); family != "" {
+		return i.HCAType + " " + family
+	}
+	return i.HCAType
+}
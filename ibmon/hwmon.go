@@ -0,0 +1,55 @@
+package ibmon
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// hwmonTempPath locates the temperature input to report for an adaptor.
+// mlx5 registers a hwmon device under <adaptor>/device/hwmon/hwmon*/ whose
+// temp*_input files hold millidegrees Celsius and whose temp*_label files
+// name the sensor ("asic", "module0", ...). The first sensor labelled as a
+// module is preferred; otherwise temp1_input (the ASIC) is used. It returns
+// "" when the adaptor has no hwmon device.
+func hwmonTempPath(adaptorPath string) string {
+	dirs, _ := filepath.Glob(filepath.Join(adaptorPath, "device", "hwmon", "hwmon*"))
+	for _, dir := range dirs {
+		labels, _ := filepath.Glob(filepath.Join(dir, "temp*_label"))
+		sort.Strings(labels)
+		for _, label := range labels {
+			data, err := os.ReadFile(label)
+			if err != nil || !strings.HasPrefix(strings.TrimSpace(string(data)), "module") {
+				continue
+			}
+			input := strings.TrimSuffix(label, "_label") + "_input"
+			if _, err := os.Stat(input); err == nil {
+				return input
+			}
+		}
+		input := filepath.Join(dir, "temp1_input")
+		if _, err := os.Stat(input); err == nil {
+			return input
+		}
+	}
+	return ""
+}
+
+// Temperature returns the interface's module temperature in degrees Celsius.
+// It fails with os.ErrNotExist if no hwmon sensor was found at discovery.
+func (i *Interface) Temperature() (float64, error) {
+	if i.TempPath == "" {
+		return 0, os.ErrNotExist
+	}
+	data, err := os.ReadFile(i.TempPath)
+	if err != nil {
+		return 0, err
+	}
+	milli, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return float64(milli) / 1000, nil
+}
@@ -0,0 +1,412 @@
+// Package ibmon discovers InfiniBand ports through sysfs and samples their
+// data counters to compute per-port throughput.
+//
+// A minimal program reading per-port Gbps looks like:
+//
+//	ifaces, err := ibmon.Discover(ibmon.Options{})
+//	time.Sleep(time.Second)
+//	t, err := ifaces[0].Sample(time.Second)
+package ibmon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultSysfsPath is where the kernel exposes InfiniBand adaptors.
+const DefaultSysfsPath = "/sys/class/infiniband"
+
+// Options controls interface discovery.
+type Options struct {
+	SysfsPath string          // root to scan; DefaultSysfsPath if empty
+	Ignore    map[string]bool // adaptor names to skip
+
+	// RxCounter and TxCounter name the files under counters/ to read
+	// instead of port_rcv_data and port_xmit_data, e.g.
+	// "port_unicast_rcv_packets". Every port must have them.
+	RxCounter string
+	TxCounter string
+
+	// CounterUnit, if set, overrides what the data counters count, which
+	// otherwise comes from the driver profile or the counter names: "words"
+	// for the 4-octet words of the InfiniBand spec, or "bytes", e.g. for a
+	// driver whose port_rcv_data already counts bytes. Packet counters
+	// cannot be overridden.
+	CounterUnit string
+
+	// Source selects what the data counters are: SourceIB, the default if
+	// empty, or SourceIPoIB.
+	Source string
+
+	// PFOnly and VFOnly limit discovery to the physical or to the virtual
+	// functions of SR-IOV adaptors (see Interface.VF); adaptors without
+	// SR-IOV count as physical.
+	PFOnly bool
+	VFOnly bool
+
+	// OnSkip, if set, is called for each adaptor or port that discovery
+	// passes over, with the name ("mlx5_0" or "mlx5_0:1") and the reason.
+	OnSkip func(name string, reason error)
+}
+
+// errIgnored is the OnSkip reason for adaptors listed in Options.Ignore.
+var errIgnored = errors.New("in the ignore list")
+
+// errNoNetdev is the OnSkip reason for ports without an IPoIB netdev under
+// SourceIPoIB.
+var errNoNetdev = errors.New("no IPoIB netdev")
+
+// Data counter sources selectable with Options.Source.
+const (
+	// SourceIB reads the port's InfiniBand data counters, as chosen by its
+	// driver profile or by RxCounter and TxCounter.
+	SourceIB = "ib"
+	// SourceIPoIB reads the byte counters of the port's IPoIB netdev
+	// instead. They only see IP traffic, headers included, so they differ
+	// from the port counters, which also see RDMA traffic. Ports without a
+	// netdev are skipped.
+	SourceIPoIB = "ipoib"
+)
+
+// Interface represents a single monitored port on an InfiniBand adaptor.
+type Interface struct {
+	Adaptor  string  // e.g. "mlx5_0"
+	Port     string  // e.g. "1", "2", etc.
+	Width    string  // link lane width, e.g. "4X" (empty if unknown)
+	Encoding string  // link encoding, e.g. "NDR" (empty if unknown)
+	Rate     string  // rate file content, e.g. "400 Gb/sec (4X NDR)" (empty if unknown)
+	MaxGbps  float64 // parsed maximum bandwidth in Gbps (0 if unknown)
+	TempPath string  // hwmon temperature input for the port's module, empty if unavailable
+	Netdev   string  // IPoIB network interface for the port, e.g. "ib0" (empty if none)
+	MTU      int     // MTU of Netdev (0 if unknown)
+	VF       bool    // the adaptor is an SR-IOV virtual function
+	GUID     string  // port GUID, e.g. "0x0002c90300a1b2c3" (empty if unknown)
+	LID      int     // port LID (0 if unknown or unassigned, as on RoCE)
+	HCAType  string  // adaptor's hca_type, e.g. "MT4125" (empty if unknown)
+	BoardID  string  // adaptor's board_id, e.g. "MT_0000000359" (empty if unknown)
+
+	// CapGbps is the rate the port should be able to run at: the fastest
+	// active rate among its adaptor's ports, since the ports of one adaptor
+	// share a capability and sysfs has no maximum rate of its own. A port
+	// whose MaxGbps is below it has negotiated down. 0 if unknown.
+	CapGbps float64
+
+	// Profile names the driver profile discovery matched the adaptor to,
+	// e.g. "mlx5", "hfi1" or "generic", or "custom" for -rx-counter and
+	// -tx-counter. CounterSource names the sysfs directory within it the
+	// data counters are read from, e.g. CountersStd, or CountersExt on
+	// drivers that only expose the 64-bit counters there. Both are empty for
+	// interfaces built with NewInterface.
+	Profile       string
+	CounterSource string
+	// Unit is what the counters count; see Sample for how it affects the
+	// reported rates.
+	Unit CounterUnit
+
+	rxPath   string // path to the RX counter file
+	txPath   string // path to the TX counter file
+	ratePath string // path to the rate file
+	portPath string // sysfs directory of the port, for state and error counters
+	prevRx   int64
+	prevTx   int64
+	prevAt   time.Time // when Sample read prevRx and prevTx; zero if it has not
+
+	prevCongestion map[string]int64 // last SampleCongestion values by counter name
+}
+
+// CounterUnit is the unit a counter file counts in.
+type CounterUnit int
+
+const (
+	UnitWords   CounterUnit = iota // 4-octet words, as in port_rcv_data
+	UnitPackets                    // packets, as in port_unicast_rcv_packets
+	UnitBytes                      // octets, as in hw_counters/rx_bytes
+)
+
+// counterUnits are the units Options.CounterUnit can name.
+var counterUnits = map[string]CounterUnit{"words": UnitWords, "bytes": UnitBytes}
+
+// counterUnit infers a counter's unit from its name: "*_data" counters count
+// words, "*_packets" counters packets and "*_bytes" counters bytes, with or
+// without a "_64" suffix.
+func counterUnit(name string) (CounterUnit, error) {
+	base := strings.TrimSuffix(name, "_64")
+	switch {
+	case strings.HasSuffix(base, "_data"):
+		return UnitWords, nil
+	case strings.HasSuffix(base, "_packets"):
+		return UnitPackets, nil
+	case strings.HasSuffix(base, "_bytes"):
+		return UnitBytes, nil
+	}
+	return 0, fmt.Errorf("counter %q: name must end in _data, _packets or _bytes", name)
+}
+
+// Standard InfiniBand counter directories; see also CountersHW.
+const (
+	CountersStd = "counters"     // port_rcv_data / port_xmit_data
+	CountersExt = "counters_ext" // port_rcv_data_64 / port_xmit_data_64
+)
+
+// NewInterface builds an Interface whose counters are read by the caller and
+// fed to Advance, rather than read from local sysfs by Sample. rate is the
+// content of the port's rate file (empty if unknown) and rx/tx are the
+// initial counter values.
+func NewInterface(adaptor, port, rate string, rx, tx int64) Interface {
+	iface := Interface{
+		Adaptor: adaptor,
+		Port:    port,
+		Rate:    normalizeRate(rate),
+		prevRx:  rx,
+		prevTx:  tx,
+	}
+	if rate != "" {
+		// For compact display, replace "Gb/sec" with "Gbps" and parse the number.
+		rate = strings.Replace(rate, "Gb/sec", "Gbps", 1)
+		if gbps, width, encoding, err := parseRate(rate); err == nil {
+			iface.MaxGbps = gbps
+			iface.Width = width
+			iface.Encoding = encoding
+		}
+	}
+	return iface
+}
+
+// Discover finds all InfiniBand interfaces (across all ports) under the sysfs
+// root and primes their counters so the first Sample has a baseline.
+func Discover(opts Options) ([]Interface, error) {
+	basePath := opts.SysfsPath
+	if basePath == "" {
+		basePath = DefaultSysfsPath
+	}
+	// Custom counters are read from counters/ only, for every adaptor;
+	// otherwise each adaptor's driver profile says where to look.
+	var custom *profile
+	if opts.RxCounter != "" || opts.TxCounter != "" {
+		rx, tx := opts.RxCounter, opts.TxCounter
+		if rx == "" {
+			rx = setStd.rx
+		}
+		if tx == "" {
+			tx = setStd.tx
+		}
+		rxUnit, err := counterUnit(rx)
+		if err != nil {
+			return nil, err
+		}
+		txUnit, err := counterUnit(tx)
+		if err != nil {
+			return nil, err
+		}
+		if rxUnit != txUnit {
+			return nil, fmt.Errorf("counters %q and %q count different units", rx, tx)
+		}
+		custom = &profile{name: "custom", sets: []counterSet{{CountersStd, rx, tx, rxUnit}}}
+	}
+
+	unit, overrideUnit := counterUnits[opts.CounterUnit]
+	switch {
+	case opts.CounterUnit != "" && !overrideUnit:
+		return nil, fmt.Errorf("unknown counter unit %q: must be words or bytes", opts.CounterUnit)
+	case overrideUnit && custom != nil && custom.sets[0].unit == UnitPackets:
+		return nil, errors.New("the unit of packet counters cannot be overridden")
+	}
+
+	ipoib := false
+	switch opts.Source {
+	case "", SourceIB:
+	case SourceIPoIB:
+		if custom != nil {
+			return nil, errors.New("custom counters cannot be read from IPoIB netdevs")
+		}
+		ipoib = true
+	default:
+		return nil, fmt.Errorf("unknown counter source %q", opts.Source)
+	}
+
+	adaptorEntries, err := os.ReadDir(basePath)
+	if err != nil {
+		return nil, err
+	}
+
+	skip := func(name string, reason error) {
+		if opts.OnSkip != nil {
+			opts.OnSkip(name, reason)
+		}
+	}
+
+	var ifaces []Interface
+	for _, entry := range adaptorEntries {
+		adaptorName := entry.Name()
+		if opts.Ignore[adaptorName] {
+			skip(adaptorName, errIgnored)
+			continue
+		}
+
+		adaptorPath := filepath.Join(basePath, adaptorName)
+		if err := checkDir(adaptorPath); err != nil {
+			skip(adaptorName, err)
+			continue
+		}
+
+		vf := isVF(adaptorPath)
+		hcaType, boardID := readAttr(adaptorPath, "hca_type"), readAttr(adaptorPath, "board_id")
+		if opts.PFOnly && vf {
+			skip(adaptorName, errIsVF)
+			continue
+		}
+		if opts.VFOnly && !vf {
+			skip(adaptorName, errNotVF)
+			continue
+		}
+
+		prof := profileFor(adaptorName)
+		switch {
+		case custom != nil:
+			prof = *custom
+		case ipoib:
+			prof = profileIPoIB
+		}
+
+		portsDir := filepath.Join(adaptorPath, "ports")
+		portEntries, err := os.ReadDir(portsDir)
+		if err != nil {
+			skip(adaptorName, err)
+			continue
+		}
+
+		for _, portEntry := range portEntries {
+			portName := portEntry.Name() // e.g. "1", "2", etc.
+			name := adaptorName + ":" + portName
+			// Port directories are real directories, never links, so the
+			// entry's own type is checked without following anything.
+			if !portEntry.IsDir() {
+				skip(name, errors.New("not a directory"))
+				continue
+			}
+			portPath := filepath.Join(portsDir, portName)
+			ratePath := filepath.Join(portPath, "rate")
+			netdev := netdevFor(adaptorPath, portName)
+
+			// The counter sets are found under the port, or under its
+			// netdev for SourceIPoIB.
+			countersPath := portPath
+			if ipoib {
+				if netdev == "" {
+					skip(name, errNoNetdev)
+					continue
+				}
+				countersPath = filepath.Join(adaptorPath, "device", "net", netdev)
+			}
+
+			// Use the first counter set where both files exist.
+			var set *counterSet
+			var rxPath, txPath string
+			var missing error // why the preferred counters were unusable
+			for _, c := range prof.sets {
+				rx := filepath.Join(countersPath, c.dir, c.rx)
+				tx := filepath.Join(countersPath, c.dir, c.tx)
+				_, err := os.Lstat(rx)
+				if err == nil {
+					_, err = os.Lstat(tx)
+				}
+				if err != nil {
+					if missing == nil {
+						missing = err
+					}
+					continue
+				}
+				set, rxPath, txPath = &c, rx, tx
+				break
+			}
+			if set == nil {
+				if custom != nil {
+					c := custom.sets[0]
+					return nil, fmt.Errorf("%s: counter file %s/%s or %s/%s not found",
+						name, c.dir, c.rx, c.dir, c.tx)
+				}
+				skip(name, fmt.Errorf("missing counter: %w", missing))
+				continue
+			}
+
+			prevRx, err := readCounter(rxPath)
+			if err != nil {
+				skip(name, err)
+				continue
+			}
+			prevTx, err := readCounter(txPath)
+			if err != nil {
+				skip(name, err)
+				continue
+			}
+
+			// Read and parse the rate.
+			rateFull, _ := readRate(ratePath)
+
+			iface := NewInterface(adaptorName, portName, rateFull, prevRx, prevTx)
+			iface.TempPath = hwmonTempPath(adaptorPath)
+			iface.Netdev = netdev
+			iface.MTU = netdevMTU(adaptorPath, iface.Netdev)
+			iface.VF = vf
+			iface.GUID = portGUID(portPath)
+			iface.LID = portLID(portPath)
+			iface.HCAType = hcaType
+			iface.BoardID = boardID
+			iface.rxPath = rxPath
+			iface.txPath = txPath
+			iface.ratePath = ratePath
+			iface.portPath = portPath
+			iface.Profile = prof.name
+			iface.CounterSource = set.dir
+			iface.Unit = set.unit
+			if overrideUnit {
+				iface.Unit = unit
+			}
+			if set.unit == UnitPackets {
+				// A packet rate cannot be compared with the link rate.
+				iface.MaxGbps = 0
+			}
+			ifaces = append(ifaces, iface)
+		}
+	}
+	setCapabilities(ifaces)
+	return ifaces, nil
+}
+
+// setCapabilities fills in CapGbps from the fastest port of each adaptor.
+func setCapabilities(ifaces []Interface) {
+	fastest := make(map[string]float64)
+	for _, iface := range ifaces {
+		fastest[iface.Adaptor] = max(fastest[iface.Adaptor], iface.MaxGbps)
+	}
+	for i := range ifaces {
+		ifaces[i].CapGbps = fastest[ifaces[i].Adaptor]
+	}
+}
+
+// checkDir reports why path is not usable as an adaptor directory. Adaptor
+// entries are normally symlinks into /sys/devices, so links are followed,
+// but resolved explicitly first so that a link cycle fails fast with a clear
+// error rather than surfacing as a generic stat failure.
+func checkDir(path string) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		if path, err = filepath.EvalSymlinks(path); err != nil {
+			return err
+		}
+		if fi, err = os.Stat(path); err != nil {
+			return err
+		}
+	}
+	if !fi.IsDir() {
+		return errors.New("not a directory")
+	}
+	return nil
+}
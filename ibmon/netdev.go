@@ -0,0 +1,54 @@
+package ibmon
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// netdevFor returns the network interface (e.g. "ib0") backing a port, or ""
+// if there is none. The adaptor's PCI device lists its netdevs under
+// device/net/, and each netdev's dev_port gives its 0-based port index;
+// older kernels only set dev_id, in hex.
+func netdevFor(adaptorPath, port string) string {
+	want, err := strconv.Atoi(port)
+	if err != nil {
+		return ""
+	}
+	dirs, _ := filepath.Glob(filepath.Join(adaptorPath, "device", "net", "*"))
+	for _, dir := range dirs {
+		idx, ok := readPortIndex(filepath.Join(dir, "dev_port"), 10)
+		if !ok || idx == 0 {
+			// dev_port is 0 on kernels that only fill in dev_id.
+			if id, ok := readPortIndex(filepath.Join(dir, "dev_id"), 0); ok {
+				idx = max(idx, id)
+			}
+		}
+		if idx+1 == want {
+			return filepath.Base(dir)
+		}
+	}
+	return ""
+}
+
+// netdevMTU returns the MTU of one of the adaptor's netdevs, or 0 if it
+// cannot be read.
+func netdevMTU(adaptorPath, netdev string) int {
+	if netdev == "" {
+		return 0
+	}
+	mtu, _ := readPortIndex(filepath.Join(adaptorPath, "device", "net", netdev, "mtu"), 10)
+	return mtu
+}
+
+// readPortIndex parses an integer sysfs attribute in the given base (0
+// accepts a "0x" prefix).
+func readPortIndex(path string, base int) (int, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(string(data)), base, 32)
+	return int(v), err == nil
+}
@@ -0,0 +1,42 @@
+package ibmon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// portGUID returns the port GUID in the canonical form ibstat and
+// ibnetdiscover print, e.g. "0x0002c90300a1b2c3", taken from the interface
+// ID half of the port's first GID, or "" if it cannot be read or is unset.
+func portGUID(portPath string) string {
+	data, err := os.ReadFile(filepath.Join(portPath, "gids", "0"))
+	if err != nil {
+		return ""
+	}
+	groups := strings.Split(strings.TrimSpace(string(data)), ":")
+	if len(groups) != 8 {
+		return ""
+	}
+	var guid uint64
+	for _, g := range groups[4:] {
+		v, err := strconv.ParseUint(g, 16, 16)
+		if err != nil {
+			return ""
+		}
+		guid = guid<<16 | v
+	}
+	if guid == 0 {
+		return ""
+	}
+	return fmt.Sprintf("0x%016x", guid)
+}
+
+// portLID returns the port's LID, or 0 if it cannot be read or none is
+// assigned, as on RoCE ports or before the subnet manager has run.
+func portLID(portPath string) int {
+	lid, _ := readPortIndex(filepath.Join(portPath, "lid"), 0)
+	return lid
+}
@@ -0,0 +1,61 @@
+package ibmon
+
+import "strings"
+
+// CountersHW is the directory of driver-defined hardware counters, used by
+// RDMA NICs that do not fill in the standard InfiniBand port counters.
+const CountersHW = "hw_counters"
+
+// CountersNetdev is the directory of a netdev's byte counters, read under
+// SourceIPoIB.
+const CountersNetdev = "statistics"
+
+// counterSet names one pair of data counter files under a port directory.
+type counterSet struct {
+	dir, rx, tx string
+	unit        CounterUnit
+}
+
+// Counter sets shared between profiles.
+var (
+	setStd = counterSet{CountersStd, "port_rcv_data", "port_xmit_data", UnitWords}
+	setExt = counterSet{CountersExt, "port_rcv_data_64", "port_xmit_data_64", UnitWords}
+	setHW  = counterSet{CountersHW, "rx_bytes", "tx_bytes", UnitBytes}
+)
+
+// profileIPoIB reads every port's IPoIB netdev counters, for SourceIPoIB.
+// Its counter set is relative to the netdev's directory, not the port's.
+var profileIPoIB = profile{name: "ipoib", sets: []counterSet{{CountersNetdev, "rx_bytes", "tx_bytes", UnitBytes}}}
+
+// profile describes where one family of drivers keeps its data counters.
+// Discovery uses the first of a port's counter sets whose files exist.
+type profile struct {
+	name     string
+	prefixes []string // adaptor name prefixes the profile applies to; none matches any
+	sets     []counterSet
+}
+
+// profiles lists the known driver profiles; the first whose prefix matches
+// the adaptor name applies, and the last is the fallback for the rest.
+var profiles = []profile{
+	// Mellanox/NVIDIA: counters/ normally, counters_ext/ on drivers that
+	// only expose the 64-bit counters there.
+	{name: "mlx5", prefixes: []string{"mlx5_", "mlx4_"}, sets: []counterSet{setStd, setExt}},
+	// Intel/Cornelis Omni-Path: the standard counters, already 64 bits wide.
+	{name: "hfi1", prefixes: []string{"hfi1_"}, sets: []counterSet{setStd}},
+	// Anything else: the InfiniBand layouts, then byte counters among the
+	// hardware counters, as exposed by e.g. efa.
+	{name: "generic", sets: []counterSet{setStd, setExt, setHW}},
+}
+
+// profileFor returns the profile for an adaptor.
+func profileFor(adaptor string) profile {
+	for _, p := range profiles {
+		for _, prefix := range p.prefixes {
+			if strings.HasPrefix(adaptor, prefix) {
+				return p
+			}
+		}
+	}
+	return profiles[len(profiles)-1]
+}
@@ -0,0 +1,106 @@
+package ibmon
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readRate reads the rate file (e.g. "400 Gb/sec (4X NDR)") and returns its trimmed content.
+func readRate(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// RefreshRate re-reads the port's rate file and, if the rate changed, e.g.
+// after the link renegotiated, updates Rate, MaxGbps, Width and Encoding to
+// match, raising CapGbps along with MaxGbps. It reports whether anything
+// changed. It fails with os.ErrNotExist for interfaces built with
+// NewInterface.
+func (i *Interface) RefreshRate() (changed bool, err error) {
+	if i.ratePath == "" {
+		return false, os.ErrNotExist
+	}
+	rate, err := readRate(i.ratePath)
+	if err != nil {
+		return false, err
+	}
+	if normalizeRate(rate) == i.Rate {
+		return false, nil
+	}
+	fresh := NewInterface(i.Adaptor, i.Port, rate, 0, 0)
+	i.Rate, i.Width, i.Encoding = fresh.Rate, fresh.Width, fresh.Encoding
+	if i.Unit != UnitPackets {
+		// A packet rate cannot be compared with the link rate.
+		i.MaxGbps = fresh.MaxGbps
+		i.CapGbps = max(i.CapGbps, i.MaxGbps)
+	}
+	return true, nil
+}
+
+// parseRate extracts the maximum bandwidth (in Gbps), lane width and encoding
+// from a rate string. For example, given "400 Gb/sec (4X NDR)", it returns
+// 400, "4X" and "NDR". The parenthetical is optional and its encoding part
+// may be absent (e.g. "10 Gb/sec (4X)"). Older kernels write less: the unit
+// may be spelled differently ("Gbps", "Gb/s"), run into the value ("40Gb/sec")
+// or be left out altogether ("40"), in which case Gbps is assumed.
+func parseRate(rateStr string) (gbps float64, width, encoding string, err error) {
+	rest := strings.TrimSpace(rateStr)
+	if open := strings.Index(rest, "("); open >= 0 {
+		end := strings.LastIndex(rest, ")")
+		if end < open {
+			return 0, "", "", fmt.Errorf("invalid rate string %q: unterminated parenthetical", rateStr)
+		}
+		detail := strings.Fields(rest[open+1 : end])
+		if len(detail) > 0 {
+			width = detail[0]
+			encoding = strings.Join(detail[1:], " ")
+		}
+		rest = strings.TrimSpace(rest[:open])
+	}
+
+	value, unit := splitRate(rest)
+	if strings.ContainsAny(unit, " \t") || !rateUnitGbps(unit) {
+		return 0, "", "", fmt.Errorf("invalid rate string %q: expected \"<value> Gb/sec\"", rateStr)
+	}
+	gbps, err = strconv.ParseFloat(value, 64)
+	if err != nil || gbps < 0 {
+		return 0, "", "", fmt.Errorf("invalid rate string %q: bad value %q", rateStr, value)
+	}
+	return gbps, width, encoding, nil
+}
+
+// splitRate splits "40 Gb/sec" or "40Gb/sec" into its leading number and the
+// unit after it, with the space between them dropped.
+func splitRate(s string) (value, unit string) {
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if end < 0 {
+		return s, ""
+	}
+	return s[:end], strings.TrimSpace(s[end:])
+}
+
+// rateUnitGbps reports whether unit is a spelling of gigabits per second the
+// kernel has used, or empty.
+func rateUnitGbps(unit string) bool {
+	switch strings.ToLower(unit) {
+	case "", "g", "gb/sec", "gb/s", "gbps", "gbit/s":
+		return true
+	}
+	return false
+}
+
+// normalizeRate returns the rate file content as shown to the user: as read,
+// unless it is a bare number, which gets its implied unit.
+func normalizeRate(rate string) string {
+	if _, err := strconv.ParseFloat(rate, 64); err == nil {
+		return rate + " Gb/sec"
+	}
+	return rate
+}
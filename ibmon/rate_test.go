@@ -0,0 +1,75 @@
+package ibmon
+
+import "testing"
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in       string
+		gbps     float64
+		width    string
+		encoding string
+		wantErr  bool
+	}{
+		{in: "400 Gb/sec (4X NDR)", gbps: 400, width: "4X", encoding: "NDR"},
+		{in: "100 Gb/sec (4X EDR)", gbps: 100, width: "4X", encoding: "EDR"},
+		{in: "10 Gb/sec (4X)", gbps: 10, width: "4X"},
+		{in: "2.5 Gb/sec (1X)", gbps: 2.5, width: "1X"},
+		{in: "200 Gbps (4X HDR)", gbps: 200, width: "4X", encoding: "HDR"},
+		{in: "56 Gb/sec", gbps: 56},
+		{in: "  25 Gb/sec (1X EDR)\n", gbps: 25, width: "1X", encoding: "EDR"},
+		// Formats written by older kernels.
+		{in: "40", gbps: 40},
+		{in: "40\n", gbps: 40},
+		{in: "2.5", gbps: 2.5},
+		{in: "40 Gb/sec", gbps: 40},
+		{in: "40Gb/sec", gbps: 40},
+		{in: "40 Gb/s (4X QDR)", gbps: 40, width: "4X", encoding: "QDR"},
+		{in: "10 gbps", gbps: 10},
+		{in: "10 (4X)", gbps: 10, width: "4X"},
+		{in: "", wantErr: true},
+		{in: "fast Gb/sec (4X)", wantErr: true},
+		{in: "-10 Gb/sec", wantErr: true},
+		{in: "100 Mb/sec", wantErr: true},
+		{in: "100 Gb/sec (4X NDR", wantErr: true},
+		{in: "100 200 Gb/sec", wantErr: true},
+		{in: "40 fast", wantErr: true},
+		{in: "1.2.3 Gb/sec", wantErr: true},
+	}
+	for _, tt := range tests {
+		gbps, width, encoding, err := parseRate(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseRate(%q): expected error, got %v", tt.in, gbps)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseRate(%q): unexpected error: %v", tt.in, err)
+			continue
+		}
+		if gbps != tt.gbps || width != tt.width || encoding != tt.encoding {
+			t.Errorf("parseRate(%q) = %v, %q, %q; want %v, %q, %q",
+				tt.in, gbps, width, encoding, tt.gbps, tt.width, tt.encoding)
+		}
+	}
+}
+
+func TestNewInterfaceRate(t *testing.T) {
+	tests := []struct {
+		rate    string
+		display string
+		gbps    float64
+	}{
+		{rate: "400 Gb/sec (4X NDR)", display: "400 Gb/sec (4X NDR)", gbps: 400},
+		{rate: "40", display: "40 Gb/sec", gbps: 40},
+		{rate: "40 Gb/sec", display: "40 Gb/sec", gbps: 40},
+		{rate: "", display: "", gbps: 0},
+	}
+	for _, tt := range tests {
+		iface := NewInterface("mlx4_0", "1", tt.rate, 0, 0)
+		if iface.Rate != tt.display || iface.MaxGbps != tt.gbps {
+			t.Errorf("NewInterface(rate %q): Rate, MaxGbps = %q, %v; want %q, %v",
+				tt.rate, iface.Rate, iface.MaxGbps, tt.display, tt.gbps)
+		}
+	}
+}
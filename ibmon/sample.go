@@ -0,0 +1,254 @@
+package ibmon
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BitsPerGbit is the size of the gigabit used for every rate in this package.
+// It is decimal (1e9 bits), the same base as the link rates the kernel reports
+// (e.g. "400 Gb/sec"), so throughput and line rate can be compared directly.
+const BitsPerGbit = 1e9
+
+// Clock returns the time readPair reads the counters at. Tests replace it to
+// control the elapsed time Sample measures.
+var Clock = time.Now
+
+// counterWordBytes is the size of one unit of the port_rcv_data and
+// port_xmit_data counters, which the IB spec defines in 4-octet words.
+const counterWordBytes = 4
+
+// Throughput holds the per-direction rates computed by Sample, along with
+// the amount of data moved since the previous sample.
+type Throughput struct {
+	RxGbps  float64
+	TxGbps  float64
+	RxBytes int64     // bytes received since the previous sample
+	TxBytes int64     // bytes transmitted since the previous sample
+	At      time.Time // when Sample read the counters; zero from Advance
+	Reset   bool      // a counter was reset since the previous sample; see Sample
+	Wraps   int       // how many of the two counters wrapped since the previous sample
+}
+
+// Sample reads the interface's counters and returns the throughput since the
+// previous Sample (or since discovery). Both rates are taken over the one
+// elapsed time between the timestamps readPair gives the two pairs of
+// readings, so a tick handled late reads neither direction high; interval is
+// only assumed for the first Sample, which has no earlier timestamp. The RX
+// and TX reads within a pair are still tens of microseconds apart (see
+// readPair), which the shared timestamp cannot correct.
+//
+// A counter that went backwards has either wrapped or been reset, as
+// counterDelta decides. A reset, e.g. by a driver reload, counts as no data
+// in that direction and sets Reset. On error the previous counter values
+// are kept, so the next Sample still spans a consistent baseline. For
+// UnitPackets interfaces the rates are in billions of packets per second
+// and no bytes are reported.
+func (i *Interface) Sample(interval time.Duration) (Throughput, error) {
+	r, err := i.ReadPair()
+	if err != nil {
+		return Throughput{}, err
+	}
+	return i.Record(r, interval), nil
+}
+
+// Reading is one read of a port's RX and TX counters, see ReadPair.
+type Reading struct {
+	Rx, Tx int64
+	At     time.Time // when the pair was read, see readPair
+}
+
+// ReadPair reads the port's counters as Sample does, but changes nothing:
+// Record then measures from the reading. Splitting Sample in two lets the
+// reads of many ports run in parallel while their baselines are only ever
+// advanced by one goroutine.
+func (i *Interface) ReadPair() (Reading, error) {
+	rx, tx, at, err := i.readPair()
+	if err != nil {
+		return Reading{}, err
+	}
+	return Reading{Rx: rx, Tx: tx, At: at}, nil
+}
+
+// Record advances the baseline to r, a reading from ReadPair, and returns
+// the throughput since the previous one, as Sample does.
+func (i *Interface) Record(r Reading, interval time.Duration) Throughput {
+	elapsed := interval
+	if !i.prevAt.IsZero() && r.At.After(i.prevAt) {
+		elapsed = r.At.Sub(i.prevAt)
+	}
+	t := i.Advance(r.Rx, r.Tx, elapsed)
+	i.prevAt = r.At
+	t.At = r.At
+	return t
+}
+
+// ReadCounters returns the raw values of the port's RX and TX counters, in
+// the interface's Unit, without advancing the baseline Sample measures from.
+func (i *Interface) ReadCounters() (rx, tx int64, err error) {
+	rx, tx, _, err = i.readPair()
+	return rx, tx, err
+}
+
+// readPair reads the RX and TX counters as close together in time as it
+// can, and returns the time between the two reads as the time of both.
+// Both files are opened before either is read, so that the path lookups
+// fall outside the window and only two read calls separate the values.
+// Some skew remains: each read makes the driver fetch that one counter from
+// the device (a firmware query on mlx5), so the directions are typically
+// sampled tens of microseconds apart. Against a 1s interval that is
+// negligible, but it grows in relative terms as the interval shrinks.
+func (i *Interface) readPair() (rx, tx int64, at time.Time, err error) {
+	if i.rxPath == "" {
+		return 0, 0, time.Time{}, os.ErrNotExist
+	}
+	rxFile, err := os.Open(i.rxPath)
+	if err != nil {
+		return 0, 0, time.Time{}, err
+	}
+	defer rxFile.Close()
+	txFile, err := os.Open(i.txPath)
+	if err != nil {
+		return 0, 0, time.Time{}, err
+	}
+	defer txFile.Close()
+
+	var rxBuf, txBuf [32]byte // a counter is at most 20 digits and a newline
+	rxN, rxErr := rxFile.Read(rxBuf[:])
+	at = Clock()
+	txN, txErr := txFile.Read(txBuf[:])
+	if rxErr != nil {
+		return 0, 0, time.Time{}, rxErr
+	}
+	if txErr != nil {
+		return 0, 0, time.Time{}, txErr
+	}
+	if rx, err = parseCounter(rxBuf[:rxN]); err != nil {
+		return 0, 0, time.Time{}, err
+	}
+	if tx, err = parseCounter(txBuf[:txN]); err != nil {
+		return 0, 0, time.Time{}, err
+	}
+	return rx, tx, at, nil
+}
+
+// Advance records counter values read elapsed after the previous ones and
+// returns the throughput between the two readings. It lets callers that read
+// the counters themselves, e.g. from another host, share Sample's rate math.
+func (i *Interface) Advance(currRx, currTx int64, elapsed time.Duration) Throughput {
+	if i.Unit == UnitPackets {
+		return i.advancePackets(currRx, currTx, elapsed)
+	}
+	scale := int64(counterWordBytes)
+	if i.Unit == UnitBytes {
+		scale = 1
+	}
+	rxDelta, rxWrap, rxReset := counterDelta(i.prevRx, currRx)
+	txDelta, txWrap, txReset := counterDelta(i.prevTx, currTx)
+	rxBytes, txBytes := rxDelta*scale, txDelta*scale
+
+	i.prevRx = currRx
+	i.prevTx = currTx
+
+	return Throughput{
+		RxGbps:  float64(rxBytes) * 8 / BitsPerGbit / elapsed.Seconds(),
+		TxGbps:  float64(txBytes) * 8 / BitsPerGbit / elapsed.Seconds(),
+		RxBytes: rxBytes,
+		TxBytes: txBytes,
+		Reset:   rxReset || txReset,
+		Wraps:   wraps(rxWrap, txWrap),
+	}
+}
+
+// counterDelta returns how far a counter advanced from prev to curr, never
+// less than 0. Some drivers expose 32-bit counters, so a drop from a value
+// that fits in 32 bits is taken as a wrap, and sets wrapped, provided the
+// distance across the wrap is under half the 32-bit range. A counter that
+// drops further than that is far more likely to have restarted near zero.
+// Such a drop, like any drop from a value above 32 bits, means the counter
+// was reset, and yields 0 with reset set.
+func counterDelta(prev, curr int64) (delta int64, wrapped, reset bool) {
+	if curr >= prev {
+		return curr - prev, false, false
+	}
+	if prev <= math.MaxUint32 {
+		if d := curr + math.MaxUint32 + 1 - prev; d < 1<<31 {
+			return d, true, false
+		}
+	}
+	return 0, false, true
+}
+
+// wraps counts the directions that wrapped.
+func wraps(rx, tx bool) int {
+	n := 0
+	for _, w := range []bool{rx, tx} {
+		if w {
+			n++
+		}
+	}
+	return n
+}
+
+// readCounter reads a counter file and returns its value.
+func readCounter(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return parseCounter(data)
+}
+
+// parseCounter parses the content of a counter file.
+func parseCounter(data []byte) (int64, error) {
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// advancePackets is Advance for packet counters. The rates are packets per
+// second in units of 1e9 (so RxGbps reads as Gpps) and no bytes are counted.
+func (i *Interface) advancePackets(currRx, currTx int64, elapsed time.Duration) Throughput {
+	rxPackets, rxWrap, rxReset := counterDelta(i.prevRx, currRx)
+	txPackets, txWrap, txReset := counterDelta(i.prevTx, currTx)
+
+	i.prevRx = currRx
+	i.prevTx = currTx
+
+	return Throughput{
+		RxGbps: float64(rxPackets) / 1e9 / elapsed.Seconds(),
+		TxGbps: float64(txPackets) / 1e9 / elapsed.Seconds(),
+		Reset:  rxReset || txReset,
+		Wraps:  wraps(rxWrap, txWrap),
+	}
+}
+
+// Counters returns the counter values the next Sample or Advance measures
+// from: those read by the latest one, or at discovery.
+func (i *Interface) Counters() (rx, tx int64) {
+	return i.prevRx, i.prevTx
+}
+
+// Rebase makes the current counter values the baseline for the next Sample,
+// discarding whatever has accumulated since the previous one. The next
+// Sample measures from the time of this read, so a rebase partway through
+// an interval does not make it read low.
+func (i *Interface) Rebase() error {
+	currRx, currTx, at, err := i.readPair()
+	if err != nil {
+		return err
+	}
+	i.RebaseTo(currRx, currTx)
+	i.prevAt = at
+	return nil
+}
+
+// RebaseTo is Rebase for callers that read the counters themselves, and so
+// pass Advance their own elapsed times. It has no time of reading, so a
+// following Sample assumes its interval.
+func (i *Interface) RebaseTo(currRx, currTx int64) {
+	i.prevRx = currRx
+	i.prevTx = currTx
+	i.prevAt = time.Time{}
+}
@@ -0,0 +1,326 @@
+package ibmon
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakePort lays out one port under a temporary sysfs root and returns a
+// function that sets its RX and TX counters.
+func fakePort(t *testing.T, root, adaptor, port, dir, rxName, txName string) func(rx, tx int64) {
+	t.Helper()
+	portPath := filepath.Join(root, adaptor, "ports", port)
+	if err := os.MkdirAll(filepath.Join(portPath, dir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(portPath, "rate"), []byte("400 Gb/sec (4X NDR)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return func(rx, tx int64) {
+		t.Helper()
+		for name, v := range map[string]int64{rxName: rx, txName: tx} {
+			path := filepath.Join(portPath, dir, name)
+			if err := os.WriteFile(path, []byte(strconv.FormatInt(v, 10)+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestSampleFromFiles(t *testing.T) {
+	root := t.TempDir()
+	set := fakePort(t, root, "mlx5_0", "1", CountersStd, "port_rcv_data", "port_xmit_data")
+	set(1000, 2000)
+
+	ifaces, err := Discover(Options{SysfsPath: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ifaces) != 1 {
+		t.Fatalf("Discover found %d interfaces, want 1", len(ifaces))
+	}
+	iface := &ifaces[0]
+	if iface.MaxGbps != 400 || iface.CounterSource != CountersStd {
+		t.Errorf("MaxGbps, CounterSource = %v, %q; want 400, %q", iface.MaxGbps, iface.CounterSource, CountersStd)
+	}
+
+	steps := []struct {
+		rx, tx         int64
+		interval       time.Duration
+		rxGbps, txGbps float64
+		rxBytes        int64
+		txBytes        int64
+	}{
+		// 1.25e9 words = 5e9 bytes = 40 Gb; over 1s that is 40 Gbps.
+		{rx: 1000 + 1.25e9, tx: 2000, interval: time.Second, rxGbps: 40, rxBytes: 5e9},
+		// The same amount over 2s halves the rate.
+		{rx: 1000 + 1.25e9, tx: 2000 + 1.25e9, interval: 2 * time.Second, txGbps: 20, txBytes: 5e9},
+		// An idle interval reads zero.
+		{rx: 1000 + 1.25e9, tx: 2000 + 1.25e9, interval: time.Second},
+	}
+	// The clock advances by each step's interval, which Sample measures
+	// rather than being told; the interval passed in is off by half, and
+	// only the first step, with no earlier reading, goes by it.
+	now := time.Unix(1000, 0)
+	Clock = func() time.Time { return now }
+	defer func() { Clock = time.Now }()
+	for n, s := range steps {
+		set(s.rx, s.tx)
+		now = now.Add(s.interval)
+		nominal := s.interval
+		if n > 0 {
+			nominal = s.interval / 2
+		}
+		got, err := iface.Sample(nominal)
+		if err != nil {
+			t.Fatalf("step %d: %v", n, err)
+		}
+		if !got.At.Equal(now) {
+			t.Errorf("step %d: At = %v, want the time of the read %v", n, got.At, now)
+		}
+		got.At = time.Time{}
+		want := Throughput{RxGbps: s.rxGbps, TxGbps: s.txGbps, RxBytes: s.rxBytes, TxBytes: s.txBytes}
+		if got != want {
+			t.Errorf("step %d: Sample = %+v, want %+v", n, got, want)
+		}
+	}
+}
+
+func TestSampleExtCounters(t *testing.T) {
+	root := t.TempDir()
+	set := fakePort(t, root, "mlx5_0", "1", CountersExt, "port_rcv_data_64", "port_xmit_data_64")
+	set(0, 0)
+
+	ifaces, err := Discover(Options{SysfsPath: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ifaces) != 1 || ifaces[0].CounterSource != CountersExt {
+		t.Fatalf("Discover = %+v, want one %s interface", ifaces, CountersExt)
+	}
+	set(250e6, 125e6)
+	got, err := ifaces[0].Sample(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RxGbps != 8 || got.TxGbps != 4 {
+		t.Errorf("Sample = %v, %v Gbps; want 8, 4", got.RxGbps, got.TxGbps)
+	}
+}
+
+func TestSampleKeepsBaselineOnError(t *testing.T) {
+	root := t.TempDir()
+	set := fakePort(t, root, "mlx5_0", "1", CountersStd, "port_rcv_data", "port_xmit_data")
+	set(0, 0)
+
+	ifaces, err := Discover(Options{SysfsPath: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	iface := &ifaces[0]
+
+	rxPath := filepath.Join(root, "mlx5_0", "ports", "1", CountersStd, "port_rcv_data")
+	if err := os.WriteFile(rxPath, []byte("garbage\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := iface.Sample(time.Second); err == nil {
+		t.Fatal("Sample of an unparsable counter: expected error")
+	}
+
+	set(250e6, 0)
+	got, err := iface.Sample(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RxGbps != 8 {
+		t.Errorf("RxGbps after a failed read = %v, want 8", got.RxGbps)
+	}
+}
+
+func TestAdvanceWrap(t *testing.T) {
+	tests := []struct {
+		name       string
+		prev, curr int64
+		bytes      int64
+		reset      bool
+		wraps      int
+	}{
+		{name: "forward", prev: 100, curr: 350, bytes: 250 * counterWordBytes},
+		{name: "32-bit wrap", prev: math.MaxUint32 - 99, curr: 150, bytes: 250 * counterWordBytes, wraps: 2},
+		{name: "wrap at the top", prev: math.MaxUint32, curr: 0, bytes: counterWordBytes, wraps: 2},
+		{name: "reset", prev: 1 << 40, curr: 10, reset: true},
+		// A 32-bit counter this far from the top restarted rather than wrapped.
+		{name: "32-bit reset", prev: 1e6, curr: 10, reset: true},
+	}
+	for _, tt := range tests {
+		iface := NewInterface("mlx5_0", "1", "", tt.prev, tt.prev)
+		got := iface.Advance(tt.curr, tt.curr, time.Second)
+		if got.RxBytes != tt.bytes || got.TxBytes != tt.bytes {
+			t.Errorf("%s: Advance(%d -> %d) moved %d, %d bytes; want %d", tt.name, tt.prev, tt.curr, got.RxBytes, got.TxBytes, tt.bytes)
+		}
+		if got.Reset != tt.reset || got.Wraps != tt.wraps {
+			t.Errorf("%s: Advance(%d -> %d) Reset, Wraps = %v, %d; want %v, %d", tt.name, tt.prev, tt.curr, got.Reset, got.Wraps, tt.reset, tt.wraps)
+		}
+		if got.RxGbps < 0 || got.TxGbps < 0 {
+			t.Errorf("%s: negative rate %v, %v", tt.name, got.RxGbps, got.TxGbps)
+		}
+	}
+}
+
+func TestAdvancePackets(t *testing.T) {
+	iface := NewInterface("mlx5_0", "1", "", 0, 0)
+	iface.Unit = UnitPackets
+	got := iface.Advance(3e9, 1e9, 2*time.Second)
+	if got.RxGbps != 1.5 || got.TxGbps != 0.5 || got.RxBytes != 0 || got.TxBytes != 0 {
+		t.Errorf("Advance = %+v, want 1.5/0.5 Gpps and no bytes", got)
+	}
+}
+
+func TestSampleHWCounters(t *testing.T) {
+	// Adaptors without a known profile fall back to byte counters under
+	// hw_counters/, which are not scaled by the word size.
+	root := t.TempDir()
+	set := fakePort(t, root, "efa_0", "1", CountersHW, "rx_bytes", "tx_bytes")
+	set(0, 0)
+
+	ifaces, err := Discover(Options{SysfsPath: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ifaces) != 1 || ifaces[0].Profile != "generic" || ifaces[0].Unit != UnitBytes {
+		t.Fatalf("Discover = %+v, want one generic interface counting bytes", ifaces)
+	}
+	set(1e9, 5e8)
+	got, err := ifaces[0].Sample(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RxGbps != 8 || got.TxGbps != 4 || got.RxBytes != 1e9 {
+		t.Errorf("Sample = %+v, want 8/4 Gbps and 1e9 bytes received", got)
+	}
+}
+
+func TestSampleIPoIB(t *testing.T) {
+	// SourceIPoIB reads the byte counters of each port's netdev, and skips
+	// ports that have none.
+	root := t.TempDir()
+	fakePort(t, root, "mlx5_0", "1", CountersStd, "port_rcv_data", "port_xmit_data")(0, 0)
+	fakePort(t, root, "mlx5_0", "2", CountersStd, "port_rcv_data", "port_xmit_data")(0, 0)
+	netdev := filepath.Join(root, "mlx5_0", "device", "net", "ib0")
+	if err := os.MkdirAll(filepath.Join(netdev, CountersNetdev), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(netdev, "dev_port"), []byte("0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	set := func(rx, tx int64) {
+		t.Helper()
+		for name, v := range map[string]int64{"rx_bytes": rx, "tx_bytes": tx} {
+			if err := os.WriteFile(filepath.Join(netdev, CountersNetdev, name), []byte(strconv.FormatInt(v, 10)+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	set(0, 0)
+
+	var skipped []string
+	ifaces, err := Discover(Options{SysfsPath: root, Source: SourceIPoIB, OnSkip: func(name string, _ error) {
+		skipped = append(skipped, name)
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ifaces) != 1 || ifaces[0].Port != "1" || ifaces[0].Profile != "ipoib" || ifaces[0].Netdev != "ib0" {
+		t.Fatalf("Discover = %+v, want port 1 read from ib0", ifaces)
+	}
+	if len(skipped) != 1 || skipped[0] != "mlx5_0:2" {
+		t.Errorf("skipped %v, want mlx5_0:2", skipped)
+	}
+	set(1e9, 5e8)
+	got, err := ifaces[0].Sample(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RxGbps != 8 || got.TxGbps != 4 {
+		t.Errorf("Sample = %+v, want 8/4 Gbps", got)
+	}
+
+	if _, err := Discover(Options{SysfsPath: root, Source: "eth"}); err == nil {
+		t.Error("Discover accepted an unknown source")
+	}
+}
+
+func TestSampleCounterUnit(t *testing.T) {
+	// The same counter deltas read as 4-octet words by default, and as bytes
+	// with the unit overridden.
+	tests := []struct {
+		unit           string
+		wantRx, wantTx float64
+	}{
+		{"", 8, 4},
+		{"words", 8, 4},
+		{"bytes", 2, 1},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		set := fakePort(t, root, "mlx5_0", "1", CountersStd, "port_rcv_data", "port_xmit_data")
+		set(0, 0)
+		ifaces, err := Discover(Options{SysfsPath: root, CounterUnit: tt.unit})
+		if err != nil {
+			t.Fatal(err)
+		}
+		set(250e6, 125e6)
+		got, err := ifaces[0].Sample(time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.RxGbps != tt.wantRx || got.TxGbps != tt.wantTx {
+			t.Errorf("unit %q: Sample = %v, %v Gbps; want %v, %v", tt.unit, got.RxGbps, got.TxGbps, tt.wantRx, tt.wantTx)
+		}
+	}
+
+	root := t.TempDir()
+	fakePort(t, root, "mlx5_0", "1", CountersStd, "port_rcv_data", "port_xmit_data")(0, 0)
+	if _, err := Discover(Options{SysfsPath: root, CounterUnit: "octets"}); err == nil {
+		t.Error("Discover accepted an unknown counter unit")
+	}
+}
+
+func TestSampleAfterRebase(t *testing.T) {
+	root := t.TempDir()
+	set := fakePort(t, root, "mlx5_0", "1", CountersStd, "port_rcv_data", "port_xmit_data")
+	set(0, 0)
+	ifaces, err := Discover(Options{SysfsPath: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	iface := &ifaces[0]
+
+	now := time.Unix(1000, 0)
+	Clock = func() time.Time { return now }
+	defer func() { Clock = time.Now }()
+	if _, err := iface.Sample(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rebased 600ms into a 1s interval, the next tick covers 400ms, at
+	// 40 Gbps rather than reading low over the full second.
+	now = now.Add(600 * time.Millisecond)
+	set(1e9, 0)
+	if err := iface.Rebase(); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(400 * time.Millisecond)
+	set(1e9+0.5e9, 0)
+	got, err := iface.Sample(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got.RxGbps-40) > 1e-9 {
+		t.Errorf("RxGbps after a rebase = %v, want 40", got.RxGbps)
+	}
+}
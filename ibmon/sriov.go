@@ -0,0 +1,21 @@
+package ibmon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// OnSkip reasons for adaptors left out by Options.PFOnly and VFOnly.
+var (
+	errIsVF  = errors.New("an SR-IOV virtual function")
+	errNotVF = errors.New("not an SR-IOV virtual function")
+)
+
+// isVF reports whether an adaptor is an SR-IOV virtual function. The PCI
+// device of a VF has a device/physfn symlink back to its physical function,
+// which physical functions and non-SR-IOV devices lack.
+func isVF(adaptorPath string) bool {
+	_, err := os.Lstat(filepath.Join(adaptorPath, "device", "physfn"))
+	return err == nil
+}
@@ -0,0 +1,25 @@
+package ibmon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LinkState returns the port's logical state as named in its sysfs state
+// file, e.g. "ACTIVE" or "DOWN" for a file containing "4: ACTIVE". It fails
+// with os.ErrNotExist for interfaces built with NewInterface.
+func (i *Interface) LinkState() (string, error) {
+	if i.portPath == "" {
+		return "", os.ErrNotExist
+	}
+	data, err := os.ReadFile(filepath.Join(i.portPath, "state"))
+	if err != nil {
+		return "", err
+	}
+	state := strings.TrimSpace(string(data))
+	if _, name, ok := strings.Cut(state, ":"); ok {
+		state = strings.TrimSpace(name)
+	}
+	return state, nil
+}
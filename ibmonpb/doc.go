@@ -0,0 +1,6 @@
+// Package ibmonpb holds the generated protobuf and gRPC code for the
+// service ibmon serves with -grpc. Edit ibmon.proto and regenerate with
+// protoc, protoc-gen-go and protoc-gen-go-grpc installed.
+package ibmonpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative ibmon.proto
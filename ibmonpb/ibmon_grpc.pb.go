@@ -0,0 +1,124 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: ibmon.proto
+
+package ibmonpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Monitor_Subscribe_FullMethodName = "/ibmon.v1.Monitor/Subscribe"
+)
+
+// MonitorClient is the client API for Monitor service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MonitorClient interface {
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Sample], error)
+}
+
+type monitorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMonitorClient(cc grpc.ClientConnInterface) MonitorClient {
+	return &monitorClient{cc}
+}
+
+func (c *monitorClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Sample], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Monitor_ServiceDesc.Streams[0], Monitor_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, Sample]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Monitor_SubscribeClient = grpc.ServerStreamingClient[Sample]
+
+// MonitorServer is the server API for Monitor service.
+// All implementations must embed UnimplementedMonitorServer
+// for forward compatibility.
+type MonitorServer interface {
+	Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[Sample]) error
+	mustEmbedUnimplementedMonitorServer()
+}
+
+// UnimplementedMonitorServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMonitorServer struct{}
+
+func (UnimplementedMonitorServer) Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[Sample]) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedMonitorServer) mustEmbedUnimplementedMonitorServer() {}
+func (UnimplementedMonitorServer) testEmbeddedByValue()                 {}
+
+// UnsafeMonitorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MonitorServer will
+// result in compilation errors.
+type UnsafeMonitorServer interface {
+	mustEmbedUnimplementedMonitorServer()
+}
+
+func RegisterMonitorServer(s grpc.ServiceRegistrar, srv MonitorServer) {
+	// If the following call pancis, it indicates UnimplementedMonitorServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Monitor_ServiceDesc, srv)
+}
+
+func _Monitor_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MonitorServer).Subscribe(m, &grpc.GenericServerStream[SubscribeRequest, Sample]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Monitor_SubscribeServer = grpc.ServerStreamingServer[Sample]
+
+// Monitor_ServiceDesc is the grpc.ServiceDesc for Monitor service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Monitor_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ibmon.v1.Monitor",
+	HandlerType: (*MonitorServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Monitor_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "ibmon.proto",
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// listEntry describes one discovered port for -list.
+type listEntry struct {
+	Host     string  `json:"host,omitempty"`
+	Adaptor  string  `json:"adaptor"`
+	Port     string  `json:"port"`
+	Rate     string  `json:"rate"`
+	MaxGbps  float64 `json:"max_gbps"`
+	VF       bool    `json:"vf,omitempty"`
+	GUID     string  `json:"guid,omitempty"`
+	LID      int     `json:"lid,omitempty"`
+	HCAType  string  `json:"hca_type,omitempty"`
+	Model    string  `json:"model,omitempty"`
+	BoardID  string  `json:"board_id,omitempty"`
+	State    string  `json:"state"`
+	Counters string  `json:"counters"` // sysfs counter directory, empty for remote ports
+}
+
+// listEntries gathers what -list reports about every monitored port. State
+// and counter source are only known for local ports.
+func (m model) listEntries() []listEntry {
+	entries := make([]listEntry, 0, len(m.statuses))
+	for _, stat := range m.statuses {
+		e := listEntry{
+			Adaptor:  stat.iface.Adaptor,
+			Port:     stat.iface.Port,
+			Rate:     stat.iface.Rate,
+			MaxGbps:  stat.iface.MaxGbps,
+			VF:       stat.iface.VF,
+			GUID:     stat.iface.GUID,
+			LID:      stat.iface.LID,
+			HCAType:  stat.iface.HCAType,
+			Model:    stat.iface.Model(),
+			BoardID:  stat.iface.BoardID,
+			Counters: stat.iface.CounterSource,
+		}
+		e.Host = stat.hostName()
+		if state, err := stat.iface.LinkState(); err == nil {
+			e.State = state
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// writeList prints the monitored ports as an aligned table, or as a JSON
+// array if asJSON is set.
+func (m model) writeList(w io.Writer, asJSON bool) error {
+	entries := m.listEntries()
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	nameWidth, rateWidth, modelWidth := len("INTERFACE"), len("RATE"), len("MODEL")
+	for i, e := range entries {
+		nameWidth = max(nameWidth, len(m.statuses[i].name()))
+		rateWidth = max(rateWidth, len(e.Rate))
+		modelWidth = max(modelWidth, len(e.Model))
+	}
+	if _, err := fmt.Fprintf(w, "%-*s  %-*s  %8s  %-8s  %-18s  %5s  %-*s  %s\n", nameWidth, "INTERFACE", rateWidth, "RATE", "MAX GBPS", "STATE", "GUID", "LID", modelWidth, "MODEL", "COUNTERS"); err != nil {
+		return err
+	}
+	for i, e := range entries {
+		lid := "-"
+		if e.LID != 0 {
+			lid = fmt.Sprint(e.LID)
+		}
+		_, err := fmt.Fprintf(w, "%-*s  %-*s  %8.0f  %-8s  %-18s  %5s  %-*s  %s\n",
+			nameWidth, m.statuses[i].name(),
+			rateWidth, dashIfEmpty(e.Rate),
+			e.MaxGbps,
+			dashIfEmpty(e.State),
+			dashIfEmpty(e.GUID),
+			lid,
+			modelWidth, dashIfEmpty(e.Model),
+			dashIfEmpty(e.Counters))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dashIfEmpty substitutes "-" for an unknown (empty) field.
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
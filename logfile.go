@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Record formats accepted by -logformat.
+const (
+	logFormatJSON = "json" // one snapshot per line, as written by -json
+	logFormatCSV  = "csv"  // one row per port per snapshot, with a header
+)
+
+// csvHeader names the columns of a -logformat csv file. host is the -remote
+// host a port was read from, empty for local ports, and stale marks a
+// remote port whose host had stopped answering.
+var csvHeader = []string{"time", "host", "adaptor", "port", "max_gbps", "rx_gbps", "tx_gbps", "rx_bytes", "tx_bytes", "stale"}
+
+// csvHeaderNoHost is the header of CSV logs written before the host and
+// stale columns, which -replay still reads.
+var csvHeaderNoHost = []string{"time", "adaptor", "port", "max_gbps", "rx_gbps", "tx_gbps", "rx_bytes", "tx_bytes"}
+
+// logSink appends every snapshot to a file, rotating it once it would grow
+// past maxSize: path.1 becomes path.2 and so on, up to keep old files, and a
+// fresh file is started at path. Each snapshot is encoded in full before it
+// is written, so rotation only ever happens between snapshots.
+type logSink struct {
+	path    string
+	format  string
+	maxSize int64 // 0 disables rotation
+	keep    int
+
+	f    *os.File
+	size int64
+}
+
+// newLogSink opens path for appending snapshots in format.
+func newLogSink(path, format string, maxSize int64, keep int) (*logSink, error) {
+	if format != logFormatJSON && format != logFormatCSV {
+		return nil, fmt.Errorf("invalid log format %q: must be %q or %q", format, logFormatJSON, logFormatCSV)
+	}
+	l := &logSink{path: path, format: format, maxSize: maxSize, keep: keep}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// open opens (or creates) the live file, writing the CSV header if it is new.
+// An existing CSV file must have the current header, so rows of different
+// layouts never end up in one file.
+func (l *logSink) open() error {
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if fi.Size() > 0 && l.format == logFormatCSV {
+		header, err := csv.NewReader(f).Read()
+		if err != nil || !slices.Equal(header, csvHeader) {
+			f.Close()
+			return fmt.Errorf("%s: existing file is not a CSV log with columns %v; move it aside", l.path, csvHeader)
+		}
+	}
+	l.f, l.size = f, fi.Size()
+	if l.size == 0 && l.format == logFormatCSV {
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write(csvHeader)
+		w.Flush()
+		return l.writeRaw(buf.Bytes())
+	}
+	return nil
+}
+
+// emit appends one snapshot, rotating first if it would overflow the file.
+func (l *logSink) emit(snap snapshot) error {
+	rec, err := l.encode(snap)
+	if err != nil {
+		return fmt.Errorf("logfile: %w", err)
+	}
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(rec)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return fmt.Errorf("logfile: %w", err)
+		}
+	}
+	if err := l.writeRaw(rec); err != nil {
+		return fmt.Errorf("logfile: %w", err)
+	}
+	return nil
+}
+
+func (l *logSink) writeRaw(b []byte) error {
+	n, err := l.f.Write(b)
+	l.size += int64(n)
+	return err
+}
+
+// encode renders a snapshot in the sink's format.
+func (l *logSink) encode(snap snapshot) ([]byte, error) {
+	var buf bytes.Buffer
+	if l.format == logFormatJSON {
+		err := json.NewEncoder(&buf).Encode(snap)
+		return buf.Bytes(), err
+	}
+	w := csv.NewWriter(&buf)
+	for _, iface := range snap.Interfaces {
+		w.Write([]string{
+			snap.Time.Format(time.RFC3339Nano),
+			iface.Host,
+			iface.Adaptor,
+			iface.Port,
+			strconv.FormatFloat(iface.MaxGbps, 'f', -1, 64),
+			strconv.FormatFloat(iface.RxGbps, 'f', -1, 64),
+			strconv.FormatFloat(iface.TxGbps, 'f', -1, 64),
+			strconv.FormatUint(iface.RxBytes, 10),
+			strconv.FormatUint(iface.TxBytes, 10),
+			strconv.FormatBool(iface.Stale),
+		})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// rotate syncs and closes the live file, shifts the numbered backups along
+// (dropping the oldest) and opens a fresh live file.
+func (l *logSink) rotate() error {
+	if err := l.f.Sync(); err != nil {
+		return err
+	}
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	if l.keep > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", l.path, l.keep))
+		for i := l.keep - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1))
+		}
+		if err := os.Rename(l.path, l.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(l.path); err != nil {
+		return err
+	}
+	return l.open()
+}
+
+// Close syncs and closes the live file.
+func (l *logSink) Close() error {
+	if err := l.f.Sync(); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+// parseSize parses a byte size such as "100MB", "512KiB" or "1048576".
+// Decimal (KB, MB, GB) and binary (KiB, MiB, GiB) suffixes are accepted.
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		mult   int64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9},
+		{"B", 1},
+	}
+	num, mult := strings.TrimSpace(s), int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(num, u.suffix) {
+			num, mult = strings.TrimSpace(strings.TrimSuffix(num, u.suffix)), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(mult)), nil
+}
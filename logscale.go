@@ -0,0 +1,22 @@
+package main
+
+import "math"
+
+// logScaleFloor is the fraction of line rate at the empty end of a
+// -log-scale bar. From there to line rate is four decades, each a quarter of
+// the bar: 0.1% fills a quarter, 1% half and 10% three quarters.
+const logScaleFloor = 1e-4
+
+// barPosition returns how far along a bar frac of line rate is drawn: at
+// frac itself, or under -log-scale on a logarithmic scale from logScaleFloor,
+// and anything below it, at the empty end to line rate at the full one. Only
+// the bars move; percentages and rates are still of line rate.
+func barPosition(frac float64, logScale bool) float64 {
+	if !logScale {
+		return frac
+	}
+	if frac <= logScaleFloor {
+		return 0
+	}
+	return min(1, math.Log10(frac/logScaleFloor)/-math.Log10(logScaleFloor))
+}
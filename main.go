@@ -1,253 +1,1434 @@
 package main
 
 import (
+	"cmp"
 	"flag"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"log"
+	"math"
 	"os"
-	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/apsu/ibmon/ibmon"
 	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/sync/errgroup"
 )
 
-// IBInterface represents a single monitored port on an InfiniBand adaptor.
-type IBInterface struct {
-	Adaptor  string // e.g. "mlx5_0"
-	Port     string // e.g. "1", "2", etc.
-	rxPath   string // path to the RX counter file
-	txPath   string // path to the TX counter file
-	ratePath string // path to the rate file
-	prevRx   int64
-	prevTx   int64
-	maxGbps  float64 // parsed maximum bandwidth in Gbps
-}
-
-// readCounter reads a counter file and returns its value.
-func readCounter(path string) (int64, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return 0, err
+// bitsPerGibit is the binary gigabit used for display under -base2. Rates are
+// always measured in decimal gigabits (ibmon.BitsPerGbit) to match the link
+// rate, and only rescaled when formatted.
+const bitsPerGibit = 1 << 30
+
+// displayUnits converts a decimal Gbps value to the unit shown on screen:
+// unchanged by default, or Gibit/s when base2 is set.
+func displayUnits(gbps float64, base2 bool) float64 {
+	if base2 {
+		return gbps * ibmon.BitsPerGbit / bitsPerGibit
 	}
-	s := strings.TrimSpace(string(data))
-	return strconv.ParseInt(s, 10, 64)
+	return gbps
 }
 
-// readRate reads the rate file (e.g. "400 Gb/sec (4X NDR)") and returns its trimmed content.
-func readRate(path string) (string, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return "", err
+// unitSuffix returns the suffix appended to displayed rates.
+func (m model) unitSuffix() string {
+	switch {
+	case m.packets:
+		return "Gp"
+	case m.base2 && m.perInterval:
+		return "Gib"
+	case m.base2:
+		return "Gi"
+	case m.perInterval:
+		return "Gb"
 	}
-	return strings.TrimSpace(string(data)), nil
+	return "G"
 }
 
-// parseRate extracts the maximum bandwidth (in Gbps) from a rate string.
-// For example, given "400 Gb/sec (4X NDR)", it returns 400.
-func parseRate(rateStr string) (float64, error) {
-	fields := strings.Fields(rateStr)
-	if len(fields) < 2 {
-		return 0, fmt.Errorf("invalid rate string: %s", rateStr)
+// Rate bases selectable with -rate-basis.
+const (
+	basisPerSecond   = "per-second"   // Gbps (default)
+	basisPerInterval = "per-interval" // gigabits moved over the last interval
+)
+
+// displayValue converts a decimal Gbps value to the figure shown on screen:
+// rescaled under -base2, and under -rate-basis per-interval multiplied out to
+// the amount moved in one interval. Percentages of line rate are always
+// computed from the per-second rate instead.
+func (m model) displayValue(gbps float64) float64 {
+	v := displayUnits(gbps, m.base2)
+	if m.perInterval {
+		v *= m.interval.Seconds()
 	}
-	return strconv.ParseFloat(fields[0], 64)
+	return v
 }
 
-// getInterfaces discovers all InfiniBand interfaces (across all ports) in /sys/class/infiniband.
-// It returns a slice of IBInterface. The ignoreList maps adaptor names to skip.
-func getInterfaces(ignoreList map[string]bool) ([]IBInterface, error) {
-	basePath := "/sys/class/infiniband"
-	adaptorEntries, err := os.ReadDir(basePath)
-	if err != nil {
-		return nil, err
+// formatRate formats a decimal Gbps value in the display units, e.g.
+// "0012.3G", or under -auto-units with the most readable unit, e.g.
+// "3.00 Mbps". The result always has the width given by rateWidth.
+// -precision sets the decimal places of the fixed format only.
+func (m model) formatRate(gbps float64) string {
+	if m.autoUnits {
+		if m.perInterval {
+			gbps *= m.interval.Seconds()
+		}
+		rate := autoRate(gbps, m.base2)
+		if m.packets {
+			rate = strings.TrimSuffix(rate, "bps") + "pps"
+		}
+		if m.perInterval {
+			// An amount, not a rate: "3.00 Mb", "1.20 Kp".
+			rate = strings.TrimSuffix(rate, "ps")
+		}
+		return fmt.Sprintf("%-*s", m.rateWidth(), rate)
 	}
+	return m.formatNumber(m.displayValue(gbps)) + m.unitSuffix()
+}
 
-	var ifaces []IBInterface
-	for _, entry := range adaptorEntries {
-		adaptorName := entry.Name()
-		if ignoreList[adaptorName] {
-			continue
-		}
+// formatNumber zero-pads v to four integer digits with m.precision decimal
+// places, e.g. "0012.3", so that fixed-unit figures line up. Under
+// -compact-numbers it pads with spaces instead, e.g. "  12.3".
+func (m model) formatNumber(v float64) string {
+	if m.compactNumbers {
+		return fmt.Sprintf("%*.*f", m.numberWidth(), m.precision, v)
+	}
+	return fmt.Sprintf("%0*.*f", m.numberWidth(), m.precision, v)
+}
 
-		adaptorPath := filepath.Join(basePath, adaptorName)
-		// Follow symlink and ensure it's a directory.
-		fi, err := os.Stat(adaptorPath)
-		if err != nil || !fi.IsDir() {
-			continue
-		}
+// numberWidth returns the width of every string produced by formatNumber.
+func (m model) numberWidth() int {
+	if m.precision == 0 {
+		return len("0000")
+	}
+	return len("0000.") + m.precision
+}
 
-		portsDir := filepath.Join(adaptorPath, "ports")
-		portEntries, err := os.ReadDir(portsDir)
-		if err != nil {
-			continue
-		}
+// rateWidth returns the width of every string produced by formatRate.
+func (m model) rateWidth() int {
+	switch {
+	case m.autoUnits && m.base2:
+		return len("1.23 Kibps")
+	case m.autoUnits:
+		return len("1.23 Kbps")
+	}
+	return m.numberWidth() + len(m.unitSuffix())
+}
 
-		for _, portEntry := range portEntries {
-			if !portEntry.IsDir() {
-				continue
-			}
-			portName := portEntry.Name() // e.g. "1", "2", etc.
-			rxPath := filepath.Join(adaptorPath, "ports", portName, "counters", "port_rcv_data")
-			txPath := filepath.Join(adaptorPath, "ports", portName, "counters", "port_xmit_data")
-			ratePath := filepath.Join(adaptorPath, "ports", portName, "rate")
-
-			// Both counter files must exist.
-			if _, err := os.Stat(rxPath); err != nil {
-				continue
-			}
-			if _, err := os.Stat(txPath); err != nil {
-				continue
-			}
+// maxPrecision is the most decimal places -precision accepts.
+const maxPrecision = 6
 
-			prevRx, err := readCounter(rxPath)
-			if err != nil {
-				continue
-			}
-			prevTx, err := readCounter(txPath)
-			if err != nil {
-				continue
-			}
+// autoPrefixes lists the prefixes tried by autoRate, smallest first.
+var autoPrefixes = []string{"", "K", "M", "G", "T"}
 
-			// Read and parse the rate.
-			rateFull, err := readRate(ratePath)
-			var maxGbps float64
-			if err == nil {
-				// For compact display, replace "Gb/sec" with "Gbps" and parse the number.
-				rateFull = strings.Replace(rateFull, "Gb/sec", "Gbps", 1)
-				maxGbps, err = parseRate(rateFull)
-				if err != nil {
-					maxGbps = 0
-				}
-			}
+// autoRate formats a decimal Gbps value with three significant figures in
+// the largest unit that keeps it at or above 1, from bps up to Tbps. With
+// base2 the units step by 1024 (Kibps, Mibps, ...) instead of 1000.
+func autoRate(gbps float64, base2 bool) string {
+	step, infix := 1000.0, ""
+	if base2 {
+		step, infix = 1024, "i"
+	}
+	v := gbps * ibmon.BitsPerGbit
+	i := 0
+	// Step up while the value would round to 1000 or more in this unit.
+	for i < len(autoPrefixes)-1 && v >= step-0.5 {
+		v /= step
+		i++
+	}
+	unit := "bps"
+	if i > 0 {
+		unit = autoPrefixes[i] + infix + "bps"
+	}
+	switch {
+	case v >= 99.95 || i == 0:
+		return fmt.Sprintf("%.0f %s", v, unit)
+	case v >= 9.995:
+		return fmt.Sprintf("%.1f %s", v, unit)
+	}
+	return fmt.Sprintf("%.2f %s", v, unit)
+}
 
-			iface := IBInterface{
-				Adaptor:  adaptorName,
-				Port:     portName,
-				rxPath:   rxPath,
-				txPath:   txPath,
-				ratePath: ratePath,
-				prevRx:   prevRx,
-				prevTx:   prevTx,
-				maxGbps:  maxGbps,
-			}
-			ifaces = append(ifaces, iface)
-		}
+// lineFraction returns value as a fraction of the line rate, capped at 1.
+// Both arguments must be in the same base; callers pass decimal Gbps.
+func lineFraction(value, maxGbps float64) float64 {
+	if maxGbps <= 0 {
+		return 0
 	}
-	return ifaces, nil
+	return math.Min(value/maxGbps, 1.0)
 }
 
-// ifaceStatus holds the current throughput values for one IBInterface.
+// idleThresholdGbps is the throughput below which a direction counts as idle.
+const idleThresholdGbps = 0.01
+
+// idleTicks is the number of consecutive idle ticks before a row is hidden,
+// so rows don't flap in and out on brief lulls.
+const idleTicks = 5
+
+// ifaceStatus holds the current throughput values for one interface.
 type ifaceStatus struct {
-	iface   IBInterface
-	rxValue float64 // current RX throughput (Gbps)
-	txValue float64 // current TX throughput (Gbps)
+	iface      ibmon.Interface
+	rxValue    float64    // current RX throughput (Gbps)
+	txValue    float64    // current TX throughput (Gbps)
+	idleStreak int        // consecutive ticks with both directions idle
+	rxHistory  []float64  // recent raw RX samples for -smooth, oldest first
+	txHistory  []float64  // recent raw TX samples for -smooth, oldest first
+	rxTotal    uint64     // bytes received since start or the last reset
+	txTotal    uint64     // bytes transmitted since start or the last reset
+	tempC      float64    // module temperature, read while the diagnostics panel is shown
+	tempOK     bool       // whether tempC holds a valid reading
+	rxPeak     float64    // highest raw RX sample this run
+	txPeak     float64    // highest raw TX sample this run
+	rxSum      float64    // sum of raw RX samples, for the run average
+	txSum      float64    // sum of raw TX samples, for the run average
+	samples    int        // number of samples folded into the sums
+	window     rateWindow // raw samples within -avg-window
+	rxAvg      float64    // mean RX over window
+	txAvg      float64    // mean TX over window
+	history    rateWindow // raw samples kept for -graph
+	hist       utilHist   // utilization distribution of the raw samples
+
+	host    *remoteHost // host the port lives on; nil for local ports
+	hostIdx int         // index of the port within host's readings
+	hostErr error       // host's latest read failure, if any
+	readAt  time.Time   // when the remote counters last fed into the rates
+	stale   bool        // no recent counter reading from host
+
+	replay    *replaySource // recording the port is played back from; nil if live
+	replayIdx int           // index of the port within replay's ports
+
+	downState string // first state other than ACTIVE seen under -fail-on-down
+
+	counterReset bool   // the latest sample found a counter reset, see ibmon.Sample
+	wraps        uint64 // counter wraps seen since start or the last reset
+
+	rateChangedAt time.Time // when -rate-refresh last saw the link rate change
+
+	congestion []ibmon.CounterDelta // congestion counters as of the latest sample, local ports only
+
+	startErrors []ibmon.Counter // error counters at startup, kept for -summary-json
+
+	paused bool // polling stopped with 'p', see togglePause
+
+	rxFlash, txFlash flash // -flash highlights of the displayed rates
+
+	bars *portBars // animated bars; nil until the first animated sample
+
+	// Raw counter values and their change over the last sample, kept only
+	// while -raw is on.
+	rawRx, rawTx           int64
+	rawRxDelta, rawTxDelta int64
+
+	poll adaptivePoll // -adaptive read schedule
+
+	// The latest tick's sample, for the emitters: sampled is set only when
+	// the port was sampled on it, and errors holds the error counters read
+	// with it for alerts.
+	sampled bool
+	last    ibmon.Throughput
+	errors  []ibmon.Counter
+
+	// Consecutive local read failures, see trackRead.
+	readErr      error
+	readFailures int
+	readRetryAt  time.Time
+}
+
+// read takes the throughput of a -remote or -replay port from its host's
+// latest reading or from the recording, and reports whether a new value is
+// available. Local ports are read by readPorts instead.
+func (s *ifaceStatus) read(interval time.Duration) (ibmon.Throughput, bool) {
+	if s.replay != nil {
+		s.stale = s.replay.stale(s.replayIdx)
+		return s.replay.reading(s.replayIdx)
+	}
+	r, at, stale, err := s.host.reading(s.hostIdx)
+	s.stale, s.hostErr = stale || !r.ok, err
+	if s.stale || !at.After(s.readAt) {
+		return ibmon.Throughput{}, false
+	}
+	elapsed := at.Sub(s.readAt)
+	s.readAt = at
+	return s.iface.Advance(r.rx, r.tx, elapsed), true
+}
+
+// name returns the row label, "mlx5_0:1", prefixed with the host for
+// remote ports.
+func (s ifaceStatus) name() string {
+	name := s.iface.Adaptor + ":" + s.iface.Port
+	if host := s.hostName(); host != "" {
+		name = host + " " + name
+	}
+	return name
+}
+
+// hostName returns the host the port lives on: its -remote host, or the one
+// a -replay recording names for it. It is empty for local ports.
+func (s ifaceStatus) hostName() string {
+	switch {
+	case s.host != nil:
+		return s.host.name
+	case s.replay != nil:
+		return s.replay.ports[s.replayIdx].host
+	}
+	return ""
+}
+
+// accumulate adds a sample's byte deltas to the running totals. Summing
+// per-sample deltas, rather than subtracting the first counter reading from
+// the latest, keeps the totals right across counter wraps; deltas that come
+// out negative are dropped rather than subtracted.
+func (s *ifaceStatus) accumulate(t ibmon.Throughput) {
+	if t.RxBytes > 0 {
+		s.rxTotal += uint64(t.RxBytes)
+	}
+	if t.TxBytes > 0 {
+		s.txTotal += uint64(t.TxBytes)
+	}
+}
+
+// formatBytes renders a byte count with binary units, e.g. "12.4 TiB".
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit && exp < 5; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// record stores the latest raw sample, keeping at most n entries of history
+// for smoothing. With n <= 1 no history is kept.
+func (s *ifaceStatus) record(rx, tx float64, n int) {
+	s.rxValue = rx
+	s.txValue = tx
+	if n <= 1 {
+		return
+	}
+	s.rxHistory = append(s.rxHistory, rx)
+	s.txHistory = append(s.txHistory, tx)
+	if len(s.rxHistory) > n {
+		s.rxHistory = s.rxHistory[len(s.rxHistory)-n:]
+		s.txHistory = s.txHistory[len(s.txHistory)-n:]
+	}
+}
+
+// displayValues returns the RX/TX throughput shown in the TUI: the simple
+// moving average over the recorded history, or the raw values without it.
+func (s ifaceStatus) displayValues() (rx, tx float64) {
+	if len(s.rxHistory) == 0 {
+		return s.rxValue, s.txValue
+	}
+	return mean(s.rxHistory), mean(s.txHistory)
+}
+
+// mean returns the arithmetic mean of vals.
+func mean(vals []float64) float64 {
+	var sum float64
+	for _, v := range vals {
+		sum += v
+	}
+	return sum / float64(len(vals))
+}
+
+// idle reports whether the interface has been idle long enough to hide.
+func (s ifaceStatus) idle() bool {
+	return s.idleStreak >= idleTicks
+}
+
+// options holds the command-line settings used to build the model.
+type options struct {
+	interval       time.Duration
+	discover       ibmon.Options
+	hideIdle       bool
+	top            int // show only this many of the busiest ports; 0 shows all
+	base2          bool
+	perInterval    bool    // -rate-basis per-interval
+	tempWarn       float64 // °C threshold for highlighting module temperatures
+	critPct        float64 // -crit, for -minimal's colors as well as alerts
+	flash          bool
+	flashPct       float64 // -flash-threshold
+	smooth         int     // moving-average window in samples; <= 1 disables
+	autoUnits      bool
+	precision      int // decimal places of fixed-unit rates, 0-6
+	compactNumbers bool
+	minimal        bool
+	noBars         bool
+	fit            bool
+	columns        int // 1 or more, or columnsAuto
+	logScale       bool
+	rtt            time.Duration
+	adaptive       bool
+	adaptiveMax    time.Duration
+	showHeaders    bool
+	rateRefresh    time.Duration // how often to re-read link rates; 0 disables
+	graphSpan      time.Duration // history to keep for -graph; 0 keeps none
+	showNetdev     bool
+	showModel      bool
+	rowColors      bool
+	raw            bool
+	failOnDown     bool
+	summaryOnQuit  bool
+	inline         bool          // -no-altscreen: draw in the normal buffer
+	barChars       barChars      // characters bars are drawn with
+	animate        bool          // glide the bars to each new sample
+	avgWindow      time.Duration // span of the displayed average; 0 disables
+	avgMarker      bool          // mark the average on each bar
+	layout         string        // layoutSplit or layoutCombined
+	direction      string        // dirBoth, or dirRX/dirTX for -rx-only/-tx-only
+	emitters       []emitter     // -json and every other sink, as set; see emitter
+	audit          *auditLog     // nil unless -audit is set
+	alerts         *alerter      // nil unless -bell or -notify is set
+	replay         *replaySource // played back instead of reading counters when set
+	state          *stateFile    // nil unless -state is set
+	summaryJSON    string        // -summary-json path; empty writes none
+	remotes        []*remoteHost // monitored instead of local ports when set
+	aggGroups      []aggGroup    // -group aggregates, validated by initialModel
+	wait           time.Duration // how long to wait for interfaces to appear; 0 fails at once
+	count          int           // quit after this many ticks; 0 for no limit
+	duration       time.Duration // quit after this long; 0 for no limit
+}
+
+// snapshotServer streams every snapshot to remote subscribers, for -grpc.
+type snapshotServer interface {
+	emitter
+	Close() error
+}
+
+// metricsExporter feeds the ports sampled in each snapshot to a push-based
+// metrics backend such as -otlp.
+type metricsExporter interface {
+	emitter
+	Shutdown() error
 }
 
 // model is our Bubble Tea model.
 type model struct {
-	statuses  []ifaceStatus
-	interval  time.Duration
-	termWidth int // current terminal width
-	vp        viewport.Model
+	statuses       []ifaceStatus
+	discovering    bool                          // the ports are still being found, see discoverPorts
+	discoverErr    error                         // why finding them failed
+	loadPorts      func() ([]ifaceStatus, error) // finds them while discovering
+	spinner        spinner.Model                 // shown while discovering
+	interval       time.Duration
+	tickGen        int       // generation of the pending tick, see tickMsg
+	tickAnchor     time.Time // ticks fall on multiples of interval from here
+	termWidth      int       // current terminal width
+	vp             viewport.Model
+	hideIdle       bool            // omit idle interfaces from the display
+	top            int             // flat view shows only the top busiest ports; 0 shows all
+	filter         textinput.Model // '/' name filter; focused while being typed
+	showTotals     bool            // show cumulative bytes moved per direction
+	base2          bool            // display Gibit/s instead of Gbit/s
+	perInterval    bool            // display gigabits per interval instead of per second
+	showDiag       bool            // show the module temperature panel
+	tempWarn       float64         // temperature (°C) above which readings are shown in red
+	critPct        float64         // percent of line rate drawn red under minimal; 0 never is
+	flash          bool            // highlight rates that just changed, see updateFlashes
+	flashPct       float64         // percent of line rate a rate must move by to flash
+	minimal        bool            // draw utilization only, see renderMinimal
+	noBars         bool            // figures only, see renderNoBars
+	fit            bool            // fit every port on screen, see renderFit
+	columns        int             // columns of rows in the flat view, see columnCount
+	logScale       bool            // draw bars on a logarithmic scale, see barPosition
+	rtt            time.Duration   // round trip for the in-flight figure; 0 hides it
+	adaptive       bool            // space out reads of idle ports, see adaptivePoll
+	adaptiveMax    time.Duration   // longest gap between an idle port's reads
+	smooth         int             // moving-average window for displayed values
+	autoUnits      bool            // format each rate in its most readable unit
+	precision      int             // decimal places of fixed-unit rates
+	compactNumbers bool            // pad fixed-unit rates with spaces, not zeros
+	rateRefresh    time.Duration   // how often to re-read link rates; 0 never does
+	ratesReadAt    time.Time       // when link rates were last re-read
+	graphSpan      time.Duration   // history kept for -graph; 0 keeps none
+	showNetdev     bool            // show each port's IPoIB netdev after its header
+	showModel      bool            // show each port's adaptor model after its header
+	showSpeed      bool            // show active vs capable rate and MTU after the header
+	showRatio      bool            // show the RX:TX balance after the header
+	showHeaders    bool            // pin column labels above the rows
+	rowColors      bool            // color each row header by port, see rowStyle
+	raw            bool            // show raw counter values under each row
+	failOnDown     bool            // track link states for -fail-on-down
+	summaryOnQuit  bool            // print the run summary on quit, not only at a run limit
+	inline         bool            // drawn in the normal buffer, not the alternate screen
+	animate        bool            // glide the bars to each new sample, see animateBars
+	barChars       barChars        // characters bars are drawn with; zero is defaultBarChars
+	packets        bool            // rates are packet rates from -rx-counter/-tx-counter
+	avgWindow      time.Duration   // span of the "(avg ...)" figure; 0 hides it
+	showAvgMarker  bool            // mark the average on each bar, see markAverage
+	layout         string          // bar layout, see layoutSplit/layoutCombined
+	direction      string          // directions drawn, see dirBoth; 'v' cycles
+	emitters       []emitter       // outputs fed every snapshot, see publish
+	audit          *auditLog       // optional plain-text record of the display
+	alerts         *alerter        // -bell/-notify alerting, also among emitters; nil if unset
+	replay         *replaySource   // -replay recording, advanced every tick
+	state          *stateFile      // optional -state file, rewritten periodically
+	summaryJSON    string          // file the run summary is written to on exit, if any
+	discover       ibmon.Options   // discovery settings, reused on SIGHUP
+
+	grouped     bool            // show one collapsible row per adaptor
+	expanded    map[string]bool // adaptors whose ports are shown in the grouped view
+	groupCursor int             // index of the selected adaptor in the grouped view
+
+	aggGroups   []aggGroup // -group aggregates, rendered after the port rows
+	showMembers bool       // also show aggregate members as their own rows
+
+	termHeight int             // current terminal height
+	showHist   bool            // show the selected port's utilization histogram
+	selected   int             // index into statuses of the selected row, -1 for none
+	selErrors  []ibmon.Counter // error counters of the selected port
+	selState   string          // link state of the selected port
+
+	started     time.Time     // when monitoring began, for -duration and the uptime
+	lastSample  time.Time     // when the latest tick was sampled, see markSampled
+	sampleGap   time.Duration // time between the latest two samples
+	ticks       int           // ticks sampled so far, for -count
+	maxTicks    int           // -count limit; 0 for none
+	maxDuration time.Duration // -duration limit; 0 for none
+
+	notice      string    // transient footer message
+	noticeUntil time.Time // when notice stops being shown
+
+	baseline   map[string]baselineRates // rates marked with 'b' by port name; nil if none
+	baselineAt time.Time                // when the baseline was marked
+
+	showHelp bool // the '?' key overlay, see renderHelp
 }
 
-// tickMsg is our message type for periodic ticks.
-type tickMsg time.Time
+// tickMsg is our message type for periodic ticks. gen tells ticks scheduled
+// before an interval change, which are dropped, from the current ones.
+type tickMsg struct {
+	t   time.Time
+	gen int
+}
 
-// tick returns a command that sends a tickMsg after the given interval.
-func tick(interval time.Duration) tea.Cmd {
-	return tea.Tick(interval, func(t time.Time) tea.Msg {
-		return tickMsg(t)
+// tick returns a command that sends a tickMsg of generation gen at the next
+// tick time after now, see nextTick. Scheduling against fixed times rather
+// than waiting out an interval after each tick keeps the time a tick takes
+// to handle from adding up, so the samples stay in phase with the anchor,
+// and so with other telemetry sampled on the same period.
+func tick(anchor time.Time, interval time.Duration, gen int) tea.Cmd {
+	return tea.Tick(time.Until(nextTick(anchor, interval, time.Now())), func(t time.Time) tea.Msg {
+		return tickMsg{t: t, gen: gen}
 	})
 }
 
+// nextTick returns the first of anchor + n*interval, n >= 1, that is after
+// now. A tick handled late skips the times it missed rather than firing
+// them in a burst.
+func nextTick(anchor time.Time, interval time.Duration, now time.Time) time.Time {
+	if now.Before(anchor) {
+		return anchor.Add(interval)
+	}
+	n := now.Sub(anchor)/interval + 1
+	return anchor.Add(n * interval)
+}
+
+// Bounds of the interval, enforced on -interval and by the +/- keys.
+const (
+	minInterval = 100 * time.Millisecond
+	maxInterval = time.Minute
+)
+
+// validateInterval reports an -interval that is not positive or is outside
+// minInterval..maxInterval. A zero interval would divide the byte deltas by
+// zero and spin the tick loop.
+func validateInterval(d time.Duration) error {
+	switch {
+	case d <= 0:
+		return fmt.Errorf("invalid -interval %v: must be positive", d)
+	case d < minInterval:
+		return fmt.Errorf("invalid -interval %v: must be at least %v", d, minInterval)
+	case d > maxInterval:
+		return fmt.Errorf("invalid -interval %v: must be at most %v", d, maxInterval)
+	}
+	return nil
+}
+
+// setInterval changes the sampling interval for the rest of the session.
+// The counters are rebased and the pending tick superseded, so the next
+// sample covers exactly one new interval rather than a mix of the two.
+func (m *model) setInterval(d time.Duration) tea.Cmd {
+	d = max(minInterval, min(maxInterval, d))
+	if d == m.interval {
+		return nil
+	}
+	m.interval = d
+	m.sampleGap = 0
+	for i := range m.statuses {
+		m.statuses[i].rebaseCounters()
+	}
+	m.tickGen++
+	m.tickAnchor = time.Now()
+	m.setNotice("interval " + d.String())
+	return tick(m.tickAnchor, d, m.tickGen)
+}
+
 // initialModel builds the initial model by discovering interfaces and initializing statuses.
-func initialModel(interval time.Duration, ignoreList map[string]bool) (model, error) {
-	ifaces, err := getInterfaces(ignoreList)
+func initialModel(opts options) (model, error) {
+	statuses, err := loadStatuses(opts, announceWait)
 	if err != nil {
 		return model{}, err
 	}
-	if len(ifaces) == 0 {
-		return model{}, fmt.Errorf("no interfaces found")
+	m := newModel(opts)
+	if err := m.setStatuses(statuses); err != nil {
+		return model{}, err
 	}
+	return m, nil
+}
+
+// loadStatuses finds the ports to show: those of the -replay or -demo
+// recording, of the -remote hosts, or discovered in sysfs. waiting, if not
+// nil, is called once if discovery has to wait for them.
+func loadStatuses(opts options, waiting func()) ([]ifaceStatus, error) {
 	var statuses []ifaceStatus
-	for _, iface := range ifaces {
-		statuses = append(statuses, ifaceStatus{
-			iface:   iface,
-			rxValue: 0,
-			txValue: 0,
-		})
+	switch {
+	case opts.replay != nil:
+		for i, iface := range opts.replay.interfaces() {
+			statuses = append(statuses, ifaceStatus{
+				iface:     iface,
+				replay:    opts.replay,
+				replayIdx: i,
+			})
+		}
+	case len(opts.remotes) > 0:
+		for _, host := range opts.remotes {
+			for i, iface := range host.interfaces() {
+				_, at, _, _ := host.reading(i)
+				statuses = append(statuses, ifaceStatus{
+					iface:   iface,
+					host:    host,
+					hostIdx: i,
+					readAt:  at,
+				})
+			}
+		}
+	default:
+		ifaces, err := discoverWait(opts.discover, opts.wait, waiting)
+		if err != nil {
+			return nil, err
+		}
+		for _, iface := range ifaces {
+			statuses = append(statuses, ifaceStatus{
+				iface:   iface,
+				rxValue: 0,
+				txValue: 0,
+			})
+		}
+	}
+	return statuses, nil
+}
+
+// setStatuses validates the ports found for the model's settings and
+// takes them on, restoring their -state and reading their starting error
+// counters as needed.
+func (m *model) setStatuses(statuses []ifaceStatus) error {
+	if err := validateAggGroups(m.aggGroups, statuses); err != nil {
+		return err
+	}
+	if m.state != nil {
+		if err := m.state.restore(statuses); err != nil {
+			return err
+		}
 	}
+	if m.summaryJSON != "" {
+		readStartErrors(statuses)
+	}
+	packets := len(statuses) > 0 && statuses[0].iface.Unit == ibmon.UnitPackets
+	if packets && m.base2 {
+		return fmt.Errorf("-base2 does not apply to packet counters")
+	}
+	m.statuses = statuses
+	m.packets = packets
+	return nil
+}
+
+// newModel builds a model with the settings in opts and no ports yet.
+func newModel(opts options) model {
 	vp := viewport.New(80, 20)
+	started := time.Now()
 	return model{
-		statuses:  statuses,
-		interval:  interval,
-		termWidth: 80,
-		vp:        vp,
-	}, nil
+		interval:       opts.interval,
+		tickAnchor:     started,
+		termWidth:      80,
+		vp:             vp,
+		hideIdle:       opts.hideIdle,
+		top:            opts.top,
+		filter:         newFilterInput(),
+		base2:          opts.base2,
+		perInterval:    opts.perInterval,
+		tempWarn:       opts.tempWarn,
+		critPct:        opts.critPct,
+		flash:          opts.flash,
+		flashPct:       opts.flashPct,
+		minimal:        opts.minimal,
+		noBars:         opts.noBars,
+		fit:            opts.fit,
+		columns:        opts.columns,
+		logScale:       opts.logScale,
+		rtt:            opts.rtt,
+		adaptive:       opts.adaptive,
+		adaptiveMax:    opts.adaptiveMax,
+		showHeaders:    opts.showHeaders,
+		smooth:         opts.smooth,
+		avgWindow:      opts.avgWindow,
+		showAvgMarker:  opts.avgMarker,
+		autoUnits:      opts.autoUnits,
+		precision:      opts.precision,
+		compactNumbers: opts.compactNumbers,
+		rateRefresh:    opts.rateRefresh,
+		ratesReadAt:    time.Now(),
+		graphSpan:      opts.graphSpan,
+		showNetdev:     opts.showNetdev,
+		showModel:      opts.showModel,
+		rowColors:      opts.rowColors,
+		raw:            opts.raw,
+		failOnDown:     opts.failOnDown,
+		summaryOnQuit:  opts.summaryOnQuit,
+		inline:         opts.inline,
+		animate:        opts.animate,
+		barChars:       opts.barChars,
+		layout:         opts.layout,
+		direction:      opts.direction,
+		emitters:       opts.emitters,
+		audit:          opts.audit,
+		alerts:         opts.alerts,
+		replay:         opts.replay,
+		state:          opts.state,
+		summaryJSON:    opts.summaryJSON,
+		discover:       rediscoverOptions(opts.discover),
+		expanded:       make(map[string]bool),
+		aggGroups:      opts.aggGroups,
+		selected:       -1,
+
+		started:     started,
+		maxTicks:    opts.count,
+		maxDuration: opts.duration,
+	}
+}
+
+// announceWait tells the user that discovery is waiting for interfaces to
+// appear.
+func announceWait() {
+	fmt.Fprintln(os.Stderr, "waiting for interfaces...")
+}
+
+// discoverWait runs discovery, retrying every second for up to wait while
+// no interfaces are found, so ibmon can start before the driver has
+// populated sysfs. A missing sysfs root counts as no interfaces yet.
+// waiting, if not nil, is called when the first attempt finds none.
+func discoverWait(opts ibmon.Options, wait time.Duration, waiting func()) ([]ibmon.Interface, error) {
+	deadline := time.Now().Add(wait)
+	announced := false
+	for {
+		ifaces, err := ibmon.Discover(opts)
+		if err == nil && len(ifaces) > 0 {
+			return ifaces, nil
+		}
+		if !time.Now().Before(deadline) {
+			if err != nil {
+				return nil, err
+			}
+			if opts.OnSkip == nil {
+				return nil, fmt.Errorf("no interfaces found (run with -verbose to see what was skipped)")
+			}
+			return nil, fmt.Errorf("no interfaces found")
+		}
+		if !announced && waiting != nil {
+			waiting()
+		}
+		announced = true
+		time.Sleep(time.Second)
+	}
+}
+
+// rediscoverOptions returns the discovery options kept for SIGHUP. Skip
+// reports are dropped since logging would scribble over the TUI.
+func rediscoverOptions(opts ibmon.Options) ibmon.Options {
+	opts.OnSkip = nil
+	return opts
+}
+
+// Bar layouts selectable with -layout.
+const (
+	layoutSplit    = "split"    // separate RX and TX bars (default)
+	layoutCombined = "combined" // one bar, RX filling from the left and TX from the right
+)
+
+// Colors used for the two halves of the combined bar; they match the ends of
+// the default progress gradient so both layouts feel alike.
+var (
+	rxBarStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#5A56E0"))
+	txBarStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#EE6FF8"))
+	emptyBarStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#606060"))
+)
+
+// refresh re-renders the viewport content. Every handler that changes what
+// renderContent shows calls it straight after mutating the model, so the
+// change appears at once instead of on the next tick. Under -no-altscreen
+// the viewport is also fitted to the content, so the frame left in the
+// scrollback is not padded out to the terminal height with blank lines.
+func (m *model) refresh() {
+	content := m.renderContent()
+	if m.inline && m.termHeight > 0 {
+		// One line is kept for View's trailing newline.
+		free := m.termHeight - lipgloss.Height(m.bottom()) - m.pinnedHeight() - 1
+		m.vp.Height = max(1, min(free, strings.Count(content, "\n")))
+	}
+	m.vp.SetContent(content)
+	m.auditFrame()
 }
 
 // renderContent builds the content (all rows) to be displayed.
-// Each row header is formatted as "mlx5_0:1 (200G): " in a fixed 18-character field.
 func (m model) renderContent() string {
+	if m.discovering || m.discoverErr != nil {
+		return m.renderDiscovery()
+	}
+	hostWidth := m.hostWidth()
+	tail := m.renderAggGroups(hostWidth)
+	if m.showDiag {
+		tail += m.renderDiagnostics()
+	}
 	var s string
-	const headerFixedWidth = 18 // fixed width for header (device:port (speed))
-	const fixed = 35            // total fixed width for non-bar parts after the header
+	switch {
+	case m.grouped:
+		s, _ = m.renderGroups()
+	case m.fit:
+		s = m.renderFit(hostWidth, m.fitLines(tail))
+	default:
+		s, _ = m.renderPorts(hostWidth)
+	}
+	return s + tail
+}
+
+// renderPorts renders a row per shown port for the flat view, followed
+// under -top by a count of the ports left out. It also returns the line on
+// which the selected row starts.
+func (m model) renderPorts(hostWidth int) (string, int) {
+	if cols := m.columnCount(); cols > 1 {
+		return m.renderColumns(hostWidth, cols)
+	}
+	var b strings.Builder
+	selectedLine := 0
+	shown, more := m.shownPorts()
+	for _, i := range shown {
+		if i == m.selected {
+			selectedLine = strings.Count(b.String(), "\n")
+		}
+		b.WriteString(m.renderPort(i, hostWidth) + "\n")
+	}
+	if more > 0 {
+		b.WriteString(footerStyle.Render(fmt.Sprintf("(… +%d more)", more)) + "\n")
+	}
+	return b.String(), selectedLine
+}
+
+// renderPort renders the row of statuses[i], followed by its -raw line if
+// they are shown.
+func (m model) renderPort(i, hostWidth int) string {
+	stat := m.statuses[i]
+	row := m.renderRow(stat.iface.Adaptor+":"+stat.iface.Port, stat, hostWidth, i == m.selected)
+	if m.raw {
+		row += "\n" + renderRaw(stat, hostWidth)
+	}
+	return row
+}
+
+// rawStyle dims the -raw counter lines.
+var rawStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#808080"))
+
+// renderRaw renders the -raw line shown under a port's row: the counter
+// values as read from sysfs, in the counters' own unit, how much each moved
+// over the last sample, and how many times the counters have wrapped. A
+// negative change means the counter wrapped or was reset; wraps that keep
+// climbing mean the port is on 32-bit counters too narrow for its rate.
+func renderRaw(stat ifaceStatus, hostWidth int) string {
+	indent := strings.Repeat(" ", hostWidth+len("mlx5_0:1   "))
+	switch {
+	case stat.replay != nil && stat.replay.demo:
+		return rawStyle.Render(indent + "-demo traffic has no raw counters")
+	case stat.replay != nil:
+		return rawStyle.Render(indent + "raw counters are not recorded in -replay logs")
+	}
+	unit := "words"
+	switch stat.iface.Unit {
+	case ibmon.UnitPackets:
+		unit = "packets"
+	case ibmon.UnitBytes:
+		unit = "bytes"
+	}
+	return rawStyle.Render(fmt.Sprintf("%srx %d (%+d)  tx %d (%+d) %s • %d wraps",
+		indent, stat.rawRx, stat.rawRxDelta, stat.rawTx, stat.rawTxDelta, unit, stat.wraps))
+}
 
+// netdevWidth returns the width of the -show-netdev column: the longest
+// netdev name plus a space either side, or zero if no port has one.
+func (m model) netdevWidth() int {
+	width := 0
 	for _, stat := range m.statuses {
-		// Format header as "mlx5_0:1 (200G): "
-		headerBase := fmt.Sprintf("%s:%s", stat.iface.Adaptor, stat.iface.Port)
-		paddedHeader := fmt.Sprintf("%-10s", headerBase)
-		header := fmt.Sprintf("%s (%dG): ", paddedHeader, int(stat.iface.maxGbps))
-		// Force the header to be exactly headerFixedWidth characters.
-		if len(header) < headerFixedWidth {
-			header = fmt.Sprintf("%-"+fmt.Sprintf("%d", headerFixedWidth)+"s", header)
-		} else if len(header) > headerFixedWidth {
-			header = header[:headerFixedWidth]
-		}
-
-		available := m.termWidth - headerFixedWidth - fixed
-		if available < 10 {
-			available = 10
-		}
-		barWidth := available / 2
-
-		// Compute progress percentages (capped at 100%).
-		rxPct, txPct := 0.0, 0.0
-		if stat.iface.maxGbps > 0 {
-			rxPct = stat.rxValue / stat.iface.maxGbps
-			if rxPct > 1.0 {
-				rxPct = 1.0
-			}
-			txPct = stat.txValue / stat.iface.maxGbps
-			if txPct > 1.0 {
-				txPct = 1.0
-			}
+		if stat.iface.Netdev != "" {
+			width = max(width, len(stat.iface.Netdev)+2)
+		}
+	}
+	return width
+}
+
+// modelTag is the short model name shown with -show-model: the adaptor's
+// family, e.g. "ConnectX-6 Dx", or its hca_type if the family is unknown.
+func modelTag(iface ibmon.Interface) string {
+	return cmp.Or(iface.Family(), iface.HCAType)
+}
+
+// modelWidth returns the width of the -show-model column: the longest model
+// tag plus a space either side, or zero if no port has one.
+func (m model) modelWidth() int {
+	width := 0
+	for _, stat := range m.statuses {
+		if tag := modelTag(stat.iface); tag != "" {
+			width = max(width, len(tag)+2)
+		}
+	}
+	return width
+}
+
+// vfMarker follows the header of ports on SR-IOV virtual functions.
+const vfMarker = " [VF]"
+
+// vfWidth returns the width of the virtual function marker column, or zero
+// if no port is on a virtual function.
+func (m model) vfWidth() int {
+	for _, stat := range m.statuses {
+		if stat.iface.VF {
+			return len(vfMarker)
+		}
+	}
+	return 0
+}
+
+// hostWidth returns the width of the leading host column: as wide as the
+// longest remote or recorded host name, or zero when only local ports are
+// shown.
+func (m model) hostWidth() int {
+	width := 0
+	for _, stat := range m.statuses {
+		if host := stat.hostName(); host != "" {
+			width = max(width, len(host)+1)
 		}
-		// Create new progress bars with the computed width.
-		rxBar := progress.New(progress.WithDefaultGradient(), progress.WithWidth(barWidth))
-		txBar := progress.New(progress.WithDefaultGradient(), progress.WithWidth(barWidth))
+	}
+	return width
+}
+
+// Widths of the fixed parts of a row, see renderRow.
+const (
+	headerFixedWidth = 18 // fixed width for header (device:port (speed))
+	splitFixed       = 35 // fixed width for non-bar parts after the header in the split layout
+	combinedFixed    = 32 // fixed width for non-bar parts after the header in the combined layout
+	singleFixed      = 16 // fixed width for non-bar parts after the header with one direction shown
+	minBarWidth      = 10 // narrowest bar worth drawing in the full layouts
+	totalsWidth      = 13 // " Σ " plus a 10-character byte count, per direction
+)
 
-		// Format percentage strings (5 characters, e.g. "  0%").
-		rxPctStr := fmt.Sprintf("%4d%%", int(rxPct*100))
-		txPctStr := fmt.Sprintf("%4d%%", int(txPct*100))
-		// Format throughput in a 7-character field (e.g. "000.0G").
-		rxVal := fmt.Sprintf("%06.1fG", stat.rxValue)
-		txVal := fmt.Sprintf("%06.1fG", stat.txValue)
+// headerWidth returns the width of a row before its first arrow: the host
+// column, the fixed header and the optional columns after it.
+func (m model) headerWidth(hostWidth int) int {
+	width := hostWidth + headerFixedWidth + m.vfWidth()
+	if m.showNetdev {
+		width += m.netdevWidth()
+	}
+	if m.showModel {
+		width += m.modelWidth()
+	}
+	if m.showSpeed {
+		width += m.speedWidth()
+	}
+	if m.showRatio {
+		width += ratioWidth
+	}
+	return width
+}
+
+// valueWidth returns the width of one direction's rate field, with the
+// average and total that may follow the rate.
+func (m model) valueWidth() int {
+	width := m.rateWidth()
+	if m.avgWindow > 0 {
+		width += len(" (avg )") + m.numberWidth()
+	}
+	width += m.baselineWidth()
+	if m.rtt > 0 {
+		width += bdpWidth
+	}
+	if m.showTotals {
+		width += totalsWidth
+	}
+	return width
+}
+
+// barSpace returns the width left for a row's bars once the fixed-width
+// fields are reserved, and the number of directions shown. The reservation
+// differs between layouts, and the per-direction fields are reserved once
+// when only one direction is shown.
+func (m model) barSpace(hostWidth int) (available, dirs int) {
+	dirs = 2
+	if m.direction != dirBoth {
+		dirs = 1
+	}
+	fixed := splitFixed
+	switch {
+	case dirs == 1:
+		fixed = singleFixed
+	case m.layout == layoutCombined:
+		fixed = combinedFixed
+	}
+	// The fixed widths assume the default "0000.0G" rate.
+	available = m.termWidth - fixed - m.headerWidth(hostWidth) - dirs*(m.valueWidth()-len("0000.0G"))
+	return available, dirs
+}
+
+// renderRow renders one interface row under the given label. The row header
+// is formatted as "mlx5_0:1 (200G): " in a fixed 18-character field, drawn
+// in the port's own color unless -no-row-colors is set, and is shown in
+// reverse video when selected.
+func (m model) renderRow(label string, stat ifaceStatus, hostWidth int, selected bool) string {
+	// Format header as "mlx5_0:1 (200G): ", or "(?G)" with no rate file.
+	paddedHeader := fmt.Sprintf("%-10s", label)
+	speed := fmt.Sprintf("%dG", int(stat.iface.MaxGbps))
+	if rateUnknown(stat.iface) {
+		speed = "?G"
+	}
+	header := fmt.Sprintf("%s (%s): ", paddedHeader, speed)
+	// Force the header to be exactly headerFixedWidth characters.
+	if len(header) < headerFixedWidth {
+		header = fmt.Sprintf("%-"+fmt.Sprintf("%d", headerFixedWidth)+"s", header)
+	} else if len(header) > headerFixedWidth {
+		header = header[:headerFixedWidth]
+	}
+	// -show-netdev widens the header by a column of IPoIB interface names.
+	netdevWidth := 0
+	if m.showNetdev {
+		netdevWidth = m.netdevWidth()
+	}
+	if netdevWidth > 0 {
+		header += fmt.Sprintf(" %-*s", netdevWidth-1, stat.iface.Netdev)
+	}
+	// -show-model adds a column of adaptor models the same way.
+	if modelWidth := m.modelWidth(); m.showModel && modelWidth > 0 {
+		header += fmt.Sprintf(" %-*s", modelWidth-1, modelTag(stat.iface))
+	}
+	// Virtual functions are marked, in a column only present if any port
+	// is one.
+	vfWidth := m.vfWidth()
+	if stat.iface.VF {
+		header += vfMarker
+	} else {
+		header += strings.Repeat(" ", vfWidth)
+	}
+	switch {
+	case rateChanged(stat):
+		header = rateChangedStyle.Render(header)
+	case selected && m.rowColors:
+		header = rowStyle(stat.name()).Inherit(selectedStyle).Render(header)
+	case selected:
+		header = selectedStyle.Render(header)
+	case m.rowColors:
+		header = rowStyle(stat.name()).Render(header)
+	}
+	// 's' adds a column of active over capable rates, after the styling so
+	// that a downgraded port keeps its own color.
+	speedWidth := 0
+	if m.showSpeed {
+		speedWidth = m.speedWidth()
+		header += renderSpeed(stat.iface, speedWidth)
+	}
+	// 'a' adds the RX:TX balance the same way.
+	if m.showRatio {
+		header += renderRatio(stat)
+	}
+
+	rxValue, txValue := stat.displayValues()
+
+	// Compute progress percentages (capped at 100%) in the same decimal
+	// base as the line rate, before any display rescaling.
+	rxPct := lineFraction(rxValue, stat.iface.MaxGbps)
+	txPct := lineFraction(txValue, stat.iface.MaxGbps)
+
+	// Format percentage strings (5 characters, e.g. "  0%").
+	rxPctStr := fmt.Sprintf("%4d%%", int(rxPct*100))
+	txPctStr := fmt.Sprintf("%4d%%", int(txPct*100))
+	// Format throughput in a 7-character field (e.g. "000.0G"), 8
+	// characters with the binary "Gi" suffix, or wider with -auto-units.
+	rxVal := stat.rxFlash.render(m.formatRate(rxValue))
+	txVal := stat.txFlash.render(m.formatRate(txValue))
+	if m.avgWindow > 0 {
+		rxVal += " (avg " + m.formatNumber(m.displayValue(stat.rxAvg)) + ")"
+		txVal += " (avg " + m.formatNumber(m.displayValue(stat.txAvg)) + ")"
+	}
+	if m.baseline != nil {
+		rxDelta, txDelta := m.baselineDeltas(stat)
+		rxVal += rxDelta
+		txVal += txDelta
+	}
+	if m.rtt > 0 {
+		rxVal += fmt.Sprintf(" bdp %10s", formatBytes(inFlight(rxValue, m.rtt)))
+		txVal += fmt.Sprintf(" bdp %10s", formatBytes(inFlight(txValue, m.rtt)))
+	}
+	if m.showTotals {
+		rxVal += fmt.Sprintf(" Σ %10s", formatBytes(stat.rxTotal))
+		txVal += fmt.Sprintf(" Σ %10s", formatBytes(stat.txTotal))
+	}
+
+	available, dirs := m.barSpace(hostWidth)
+
+	hostCol := ""
+	if hostWidth > 0 {
+		hostCol = fmt.Sprintf("%-*s", hostWidth, stat.hostName())
+	}
+
+	var line string
+	switch {
+	case stat.stale:
+		msg := "stale: no recent data from host"
+		if stat.hostErr != nil {
+			msg = "stale: " + strings.Join(strings.Fields(stat.hostErr.Error()), " ")
+		}
+		line = hostCol + header + staleStyle.MaxWidth(max(1, m.termWidth-lipgloss.Width(hostCol+header))).Render(msg)
+	case stat.counterReset:
+		line = hostCol + header + staleStyle.Render("counter reset: rates resume with the next sample")
+	case stat.paused:
+		line = hostCol + header + staleStyle.Render("[paused] not polled ('p' resumes)")
+	case stat.readFailing():
+		msg := "[read error: " + stat.readError() + "]"
+		line = hostCol + header + warnStyle.MaxWidth(max(1, m.termWidth-lipgloss.Width(hostCol+header))).Render(msg)
+	case m.minimal:
+		line = hostCol + m.renderMinimal(label, stat, hostWidth, selected)
+	case m.noBars:
+		line = hostCol + header + m.renderNoBars(rxPct, txPct, rxVal, txVal)
+	case available < dirs*minBarWidth:
+		// Too narrow for the full row without wrapping.
+		rows := strings.SplitN(compactRows(label, m.termWidth-hostWidth, rxPct, txPct, m.barChars, m.logScale), "\n", 2)
+		if selected {
+			rows[0] = selectedStyle.Render(rows[0][:10]) + rows[0][10:]
+		}
+		line = hostCol + rows[0] + "\n" + strings.Repeat(" ", hostWidth) + rows[1]
+	case dirs == 1:
+		// Build the row, with the one bar as wide as both would be:
+		// [header] + "↓ " + [bar] + " " + [pctStr] + " " + [val]
+		rxBar, txBar := m.barViews(stat, available, rxPct, txPct)
+		rxBar, txBar = m.markAverages(stat, rxBar, txBar)
+		if m.direction == dirTX {
+			line = hostCol + header + fmt.Sprintf("%s %s %s %s", txArrow(), txBar, txPctStr, txVal)
+		} else {
+			line = hostCol + header + fmt.Sprintf("%s %s %s %s", rxArrow(), rxBar, rxPctStr, rxVal)
+		}
+	case m.layout == layoutCombined:
+		// Build the row:
+		// [header] + "↓ " + [rxVal] + " " + [rxPctStr] + " " + [bar] + " " + [txPctStr] + " " + [txVal] + " ↑"
+		bar := combinedBar(available, barPosition(rxPct, m.logScale), barPosition(txPct, m.logScale), m.barChars)
+		if m.showAvgMarker {
+			rxAvgPct, txAvgPct := m.averagePositions(stat)
+			bar = markCombined(bar, available, rxAvgPct, txAvgPct)
+		}
+		line = hostCol + header + fmt.Sprintf("%s %s %s %s %s %s %s", rxArrow(), rxVal, rxPctStr, bar, txPctStr, txVal, txArrow())
+	default:
+		rxBar, txBar := m.barViews(stat, available/2, rxPct, txPct)
+		rxBar, txBar = m.markAverages(stat, rxBar, txBar)
 
 		// Build the row:
-		// [header] + "↑ " + [rxBar] + " " + [rxPctStr] + " " + [rxVal] + "   ↓ " + [txBar] + " " + [txPctStr] + " " + [txVal]
-		line := header + fmt.Sprintf("↑ %s %s %s   ↓ %s %s %s", rxBar.ViewAs(rxPct), rxPctStr, rxVal, txBar.ViewAs(txPct), txPctStr, txVal)
-		s += line + "\n"
+		// [header] + "↓ " + [rxBar] + " " + [rxPctStr] + " " + [rxVal] + "   ↑ " + [txBar] + " " + [txPctStr] + " " + [txVal]
+		line = hostCol + header + fmt.Sprintf("%s %s %s %s   %s %s %s %s", rxArrow(), rxBar, rxPctStr, rxVal, txArrow(), txBar, txPctStr, txVal)
+	}
+	return line
+}
+
+// Status colors: okColor for a port within its limits, warnColor for one
+// that needs a look, such as a downgraded link or a busy -minimal row, and
+// critColor for one past a threshold.
+const (
+	okColor   = lipgloss.Color("#87D75F")
+	warnColor = lipgloss.Color("#D7D75F")
+	critColor = lipgloss.Color("#FF5F5F")
+)
+
+// rowPalette holds the row header colors. The status colors and their
+// neighbors, greens, yellows and reds, are left out so they keep meaning a
+// state, and grays so they keep meaning stale or inactive.
+var rowPalette = []lipgloss.Color{
+	"#5FD7AF", "#5F87FF", "#5F87D7", "#5FAFD7", "#AF87FF", "#5FD7D7",
+	"#AF87D7", "#87AFFF", "#D7AFFF", "#D787D7", "#87D7FF", "#FF87D7",
+}
+
+// rowStyle returns the header style for the row named name. The color is
+// picked by hashing the name, so a port keeps it across scrolling,
+// rediscovery and restarts.
+func rowStyle(name string) lipgloss.Style {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return lipgloss.NewStyle().Foreground(rowPalette[h.Sum32()%uint32(len(rowPalette))])
+}
+
+// staleStyle marks remote rows whose host has stopped answering.
+var staleStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#808080")).Italic(true)
+
+// warnStyle highlights readings that exceed a warning threshold.
+var warnStyle = lipgloss.NewStyle().Foreground(critColor).Bold(true)
+
+// renderDiagnostics builds the module temperature panel shown below the rows.
+func (m model) renderDiagnostics() string {
+	s := "\nModule temperature:\n"
+	for _, stat := range m.statuses {
+		name := fmt.Sprintf("%-10s", stat.iface.Adaptor+":"+stat.iface.Port)
+		switch {
+		case !stat.tempOK:
+			s += fmt.Sprintf("  %s    n/a\n", name)
+		case stat.tempC > m.tempWarn:
+			s += fmt.Sprintf("  %s %s\n", name, warnStyle.Render(fmt.Sprintf("%5.1f°C", stat.tempC)))
+		default:
+			s += fmt.Sprintf("  %s %5.1f°C\n", name, stat.tempC)
+		}
 	}
 	return s
 }
 
+// readRaw fills in the -raw counter values from the latest sample, with no
+// change yet, for when -raw is switched on between samples.
+func (m *model) readRaw() {
+	for i := range m.statuses {
+		s := &m.statuses[i]
+		s.rawRx, s.rawTx = s.iface.Counters()
+		s.rawRxDelta, s.rawTxDelta = 0, 0
+	}
+}
+
+// readTemperatures refreshes the module temperature of every interface.
+func (m *model) readTemperatures() {
+	for i := range m.statuses {
+		t, err := m.statuses[i].iface.Temperature()
+		m.statuses[i].tempC = t
+		m.statuses[i].tempOK = err == nil
+	}
+}
+
+// compactRows renders an interface as one line per direction, dropping the
+// speed and throughput fields so it fits terminals too narrow for a full row:
+//
+//	mlx5_0:1   ↓ [bar]  12%
+//	           ↑ [bar]   3%
+//
+// The bars are drawn with chars, on a logarithmic scale if logScale is set.
+func compactRows(label string, termWidth int, rxPct, txPct float64, chars barChars, logScale bool) string {
+	const fixed = 19 // name (10) + " ↓ " (3) + " " (1) + percent (5)
+	barWidth := termWidth - fixed
+	if barWidth < 5 {
+		barWidth = 5
+	}
+	bar := progress.New(progress.WithDefaultGradient(), progress.WithWidth(barWidth), progress.WithoutPercentage(), chars.option())
+
+	name := fmt.Sprintf("%-10s", label)
+	if len(name) > 10 {
+		name = name[:10]
+	}
+	rxLine := fmt.Sprintf("%s %s %s %4d%%", name, rxArrow(), bar.ViewAs(barPosition(rxPct, logScale)), int(rxPct*100))
+	txLine := fmt.Sprintf("%10s %s %s %4d%%", "", txArrow(), bar.ViewAs(barPosition(txPct, logScale)), int(txPct*100))
+	return rxLine + "\n" + txLine
+}
+
+// rxArrow and txArrow mark the receive and transmit figures of a row. Down
+// is receive and up is transmit, each in its half's combined-bar color.
+func rxArrow() string { return rxBarStyle.Render("↓") }
+func txArrow() string { return txBarStyle.Render("↑") }
+
+// combinedBar renders a single bar of the given width in which RX fills the
+// left half from the left edge and TX fills the right half from the right
+// edge, so two saturated directions meet in the middle. It is drawn with
+// chars.
+func combinedBar(width int, rxPct, txPct float64, chars barChars) string {
+	rxHalf := width / 2
+	txHalf := width - rxHalf
+	rxFilled := int(math.Round(rxPct * float64(rxHalf)))
+	txFilled := int(math.Round(txPct * float64(txHalf)))
+
+	rxFull, empty := chars.fill(rxFilled, rxHalf-rxFilled+txHalf-txFilled)
+	txFull, _ := chars.fill(txFilled, 0)
+	return rxBarStyle.Render(rxFull) + emptyBarStyle.Render(empty) + txBarStyle.Render(txFull)
+}
+
+// sample updates throughput values for each interface. The sinks get the
+// results from the tick's snapshot, see publish.
+func (m *model) sample() {
+	m.ticks++
+	if m.replay != nil {
+		m.replay.advance()
+	}
+	now := time.Now()
+	m.refreshRates(now)
+	reads := m.readPorts(now)
+	for i := range m.statuses {
+		t, ok := m.applyRead(i, reads[i], now)
+		m.statuses[i].sampled = ok
+		if !ok {
+			continue
+		}
+		m.statuses[i].last, m.statuses[i].errors = t, reads[i].errors
+		// Rates can't be negative; a recording or a remote counter that
+		// went backwards still reads as zero rather than a negative bar.
+		rxGbps, txGbps := max(0, t.RxGbps), max(0, t.TxGbps)
+		if m.adaptivePolled(m.statuses[i]) {
+			idle := rxGbps < idleThresholdGbps && txGbps < idleThresholdGbps
+			m.statuses[i].poll.schedule(now, idle, m.interval, m.adaptiveMax)
+		}
+		m.statuses[i].counterReset = t.Reset
+		m.statuses[i].wraps += uint64(t.Wraps)
+		if t.Reset {
+			m.setNotice(m.statuses[i].name() + ": counter reset, sample skipped")
+		}
+		m.statuses[i].record(rxGbps, txGbps, m.smooth)
+		m.statuses[i].accumulate(t)
+		m.statuses[i].trackPeaks(rxGbps, txGbps)
+		m.statuses[i].hist.add(rxGbps, txGbps, m.statuses[i].iface.MaxGbps)
+		if m.avgWindow > 0 {
+			w := &m.statuses[i].window
+			w.span = m.avgWindow
+			w.add(time.Now(), rxGbps, txGbps)
+			m.statuses[i].rxAvg, m.statuses[i].txAvg = w.average()
+		}
+		if m.graphSpan > 0 {
+			h := &m.statuses[i].history
+			h.span = m.graphSpan
+			h.add(time.Now(), rxGbps, txGbps)
+		}
+
+		// Interfaces are polled even while hidden, so any traffic resets
+		// the streak and the row reappears on the very next render.
+		if rxGbps < idleThresholdGbps && txGbps < idleThresholdGbps {
+			m.statuses[i].idleStreak++
+		} else {
+			m.statuses[i].idleStreak = 0
+		}
+	}
+	if m.flash {
+		m.updateFlashes()
+	}
+	if m.showDiag {
+		m.readTemperatures()
+	}
+	if m.state != nil {
+		if err := m.state.saveDue(m.statuses, time.Now()); err != nil {
+			m.setNotice("state: " + err.Error())
+		}
+	}
+}
+
+// readWorkers bounds how many ports readPorts reads at once.
+const readWorkers = 8
+
+// portRead is what readPorts read of one local port: its counters, link
+// state for -fail-on-down, congestion counters and error counters for
+// alerts, or the error that kept the counters from being read. None of it
+// has been applied to the port.
+type portRead struct {
+	due        bool // the port was read this tick
+	counters   ibmon.Reading
+	err        error
+	link       string
+	linkErr    error
+	congestion []ibmon.Counter
+	errors     []ibmon.Counter // nil unless alerting and readable
+}
+
+// readPorts does the sysfs I/O of a tick for every local port that is due a
+// read, several ports at a time, so that a node with many ports keeps up
+// with a short interval. The workers only read: each returns what it found
+// in its own slot, and sample applies it all on the Update goroutine once
+// every read is done, so no port's state changes while the pool runs.
+// Paused ports, failing ones awaiting a retry and, with -adaptive, idle
+// ones not yet due are skipped; -remote and -replay ports have nothing to
+// read here.
+func (m model) readPorts(now time.Time) []portRead {
+	reads := make([]portRead, len(m.statuses))
+	var g errgroup.Group
+	g.SetLimit(readWorkers)
+	for i, s := range m.statuses {
+		if s.host != nil || s.replay != nil || s.paused || !s.readDue(now) {
+			continue
+		}
+		if m.adaptivePolled(s) && !s.poll.due(now, m.interval) {
+			continue
+		}
+		checkLink := m.failOnDown && s.downState == ""
+		readErrors := m.alerts != nil
+		g.Go(func() error {
+			r := portRead{due: true}
+			if checkLink {
+				r.link, r.linkErr = s.iface.LinkState()
+			}
+			r.counters, r.err = s.iface.ReadPair()
+			if r.err == nil {
+				r.congestion, _ = s.iface.ReadCongestion()
+				if readErrors {
+					r.errors, _ = s.iface.ErrorCounters()
+				}
+			}
+			reads[i] = r
+			return nil
+		})
+	}
+	g.Wait()
+	return reads
+}
+
+// applyRead brings port i up to date with what readPorts read of it, or
+// with its latest -remote or -replay reading, and returns the throughput
+// since its previous sample if there is a new one.
+func (m *model) applyRead(i int, r portRead, now time.Time) (ibmon.Throughput, bool) {
+	s := &m.statuses[i]
+	if s.paused {
+		return ibmon.Throughput{}, false
+	}
+	prevRx, prevTx := s.iface.Counters()
+	var t ibmon.Throughput
+	if s.host != nil || s.replay != nil {
+		var ok bool
+		if t, ok = s.read(m.interval); !ok {
+			return t, false
+		}
+	} else {
+		if !r.due {
+			return t, false
+		}
+		if r.link != "" || r.linkErr != nil {
+			s.noteLink(r.link, r.linkErr)
+		}
+		s.trackRead(r.err, now)
+		if r.err != nil {
+			return t, false
+		}
+		interval := m.interval
+		if m.adaptivePolled(*s) {
+			interval = s.poll.elapsed(now, m.interval)
+		}
+		t = s.iface.Record(r.counters, interval)
+		s.congestion = s.iface.RecordCongestion(r.congestion)
+	}
+	if m.raw {
+		s.rawRx, s.rawTx = s.iface.Counters()
+		s.rawRxDelta, s.rawTxDelta = s.rawRx-prevRx, s.rawTx-prevTx
+	}
+	return t, true
+}
+
 func (m model) Init() tea.Cmd {
-	return tea.Batch(tick(m.interval))
+	if m.discovering {
+		return tea.Batch(m.spinner.Tick, m.discoverPorts())
+	}
+	return tea.Batch(tick(m.tickAnchor, m.interval, m.tickGen))
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -256,63 +1437,457 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 
 	case tickMsg:
-		// Update throughput values for each interface.
-		for i, s := range m.statuses {
-			currRx, err := readCounter(s.iface.rxPath)
-			if err != nil {
-				continue
-			}
-			currTx, err := readCounter(s.iface.txPath)
-			if err != nil {
-				continue
+		if msg.gen != m.tickGen {
+			return m, nil
+		}
+		m.sample()
+		m.markSampled(time.Now())
+		if err := m.publish(m.snapshot(msg.t)); err != nil {
+			m.setNotice(err.Error())
+		}
+		if m.alerts != nil {
+			if msg := m.alerts.take(); msg != "" {
+				m.setNotice(msg)
 			}
-			diffRx := currRx - s.iface.prevRx
-			diffTx := currTx - s.iface.prevTx
+		}
+		m.readSelected()
+		m.relayout()
+		if m.limitReached(msg.t) {
+			return m, tea.Quit
+		}
+		cmds = append(cmds, tick(m.tickAnchor, m.interval, m.tickGen), m.animateBars())
 
-			m.statuses[i].iface.prevRx = currRx
-			m.statuses[i].iface.prevTx = currTx
+	case discoveredMsg:
+		cmd := m.handleDiscovered(msg)
+		return m, cmd
 
-			rxGbps := float64(diffRx) * 8 / 1e9 / m.interval.Seconds()
-			txGbps := float64(diffTx) * 8 / 1e9 / m.interval.Seconds()
-			m.statuses[i].rxValue = rxGbps
-			m.statuses[i].txValue = txGbps
+	case spinner.TickMsg:
+		if !m.discovering {
+			return m, nil
 		}
-		m.vp.SetContent(m.renderContent())
-		cmds = append(cmds, tick(m.interval))
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		m.refresh()
+		return m, cmd
+
+	case progress.FrameMsg:
+		cmd := m.updateBars(msg)
+		m.refresh()
+		return m, cmd
 
 	case tea.WindowSizeMsg:
-		m.termWidth = msg.Width
-		m.vp.Width = msg.Width
-		m.vp.Height = msg.Height - 1 // leave room if needed
-		m.vp.SetContent(m.renderContent())
+		m.resize(msg.Width, msg.Height)
 		return m, nil
 
+	case signalMsg:
+		return m.handleSignal(msg.sig)
+
+	case copiedMsg:
+		m.handleCopied(msg)
+		m.relayout()
+		return m, nil
+
+	case tea.MouseMsg:
+		// Mouse wheel scrolling is handled by the viewport itself.
+		var cmd tea.Cmd
+		m.vp, cmd = m.vp.Update(msg)
+		return m, cmd
+
 	case tea.KeyMsg:
+		if m.discovering || m.discoverErr != nil {
+			// Nothing to act on yet but quitting.
+			if k := msg.String(); k == "q" || k == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+		if m.filter.Focused() {
+			return m.updateFilter(msg)
+		}
+		if m.showHelp && msg.String() == "esc" {
+			m.showHelp = false
+			return m, nil
+		}
+		// Keys handled here are not forwarded to the viewport, so command
+		// keys never double as its scroll bindings.
 		switch msg.String() {
 		case "q", "ctrl+c":
 			return m, tea.Quit
+		case "?":
+			m.showHelp = !m.showHelp
+		case "h":
+			m.hideIdle = !m.hideIdle
+			m.refresh()
+		case "/":
+			return m, m.openFilter()
+		case "s":
+			m.showSpeed = !m.showSpeed
+			m.refresh()
+		case "a":
+			m.showRatio = !m.showRatio
+			m.refresh()
+		case "y":
+			return m, copySnapshot(m.copyText(time.Now()))
+		case "o":
+			m.toggleAvgMarker()
+			m.refresh()
+		case "b":
+			m.toggleBaseline(time.Now())
+			m.relayout()
+		case "p":
+			m.togglePause()
+			m.refresh()
+		case "v":
+			m.cycleDirection()
+			m.refresh()
+		case "L":
+			m.showHeaders = !m.showHeaders
+			m.relayout()
+		case "T":
+			m.cycleTop()
+			m.refresh()
+		case "c":
+			m.showTotals = !m.showTotals
+			m.refresh()
+		case "H":
+			m.showHist = !m.showHist
+			if _, ok := m.selectedStatus(); m.showHist && !ok {
+				m.setNotice("select a port with ↑/↓ to see its utilization histogram")
+			}
+			m.relayout()
+		case "x":
+			m.raw = !m.raw
+			if m.raw {
+				m.readRaw()
+			}
+			m.refresh()
+		case "d":
+			m.showDiag = !m.showDiag
+			if m.showDiag {
+				m.readTemperatures()
+			}
+			m.refresh()
+		case "r":
+			for i := range m.statuses {
+				m.statuses[i].rxTotal = 0
+				m.statuses[i].txTotal = 0
+			}
+			m.refresh()
+		case "m":
+			m.showMembers = !m.showMembers
+			m.refresh()
+		case "R":
+			for i := range m.statuses {
+				m.statuses[i].rebase()
+			}
+			m.setNotice("reset all counters, peaks and averages")
+			m.refresh()
+		case "+", "=":
+			cmds = append(cmds, m.setInterval(m.interval/2))
+			m.relayout()
+		case "-":
+			cmds = append(cmds, m.setInterval(m.interval*2))
+			m.relayout()
+		case "G":
+			m.grouped = !m.grouped
+			m.relayout() // the detail block is flat-view only
+		case "up", "k", "down", "j", "enter", "esc":
+			if !m.grouped {
+				// In the flat view the arrows select a row to detail.
+				switch msg.String() {
+				case "up", "k":
+					m.moveSelection(-1)
+				case "down", "j":
+					m.moveSelection(1)
+				case "esc":
+					m.clearSelection()
+				}
+				return m, nil
+			}
+			switch msg.String() {
+			case "up", "k":
+				m.moveGroupCursor(-1)
+			case "down", "j":
+				m.moveGroupCursor(1)
+			case "enter":
+				m.toggleGroup()
+			}
+		case "pgup":
+			m.vp.ViewUp()
+		case "pgdown":
+			m.vp.ViewDown()
+		case "home":
+			m.vp.GotoTop()
+		case "end":
+			m.vp.GotoBottom()
 		default:
 			var cmd tea.Cmd
 			m.vp, cmd = m.vp.Update(msg)
-			cmds = append(cmds, cmd)
+			return m, cmd
 		}
+		return m, nil
 	}
 
 	var vpCmd tea.Cmd
 	m.vp, vpCmd = m.vp.Update(msg)
 	cmds = append(cmds, vpCmd)
+	if m.filter.Focused() {
+		// Keep the filter's cursor blinking.
+		var filterCmd tea.Cmd
+		m.filter, filterCmd = m.filter.Update(msg)
+		cmds = append(cmds, filterCmd)
+	}
 
 	return m, tea.Batch(cmds...)
 }
 
+// footerStyle renders the key hint line below the viewport.
+var footerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#808080"))
+
+// legend explains the direction arrows and their colors, and shows the
+// current sampling interval and name filter, the uptime and how recent the
+// latest sample is.
+func (m model) legend() string {
+	state := " TX transmit  • every " + m.interval.String()
+	if m.perInterval {
+		state += ", " + m.unitSuffix() + " per interval"
+	}
+	if m.logScale {
+		state += " • log scale bars"
+	}
+	if m.adaptive {
+		state += " • adaptive polling"
+	}
+	if m.baseline != nil {
+		state += " • Δ from " + m.baselineAt.Format("15:04:05")
+	}
+	if m.discover.Source == ibmon.SourceIPoIB {
+		state += " • IPoIB netdev counters"
+	}
+	if f := m.filter.Value(); f != "" && !m.filter.Focused() {
+		state += " • filter /" + f
+	}
+	return rxArrow() + footerStyle.Render(" RX receive  ") + txArrow() + footerStyle.Render(state) + m.clock(time.Now())
+}
+
+// footer returns the arrow legend and the key hint line shown beneath the
+// interface rows.
+func (m model) footer() string {
+	hints := helpHints
+	if m.notice != "" && time.Now().Before(m.noticeUntil) {
+		hints = m.notice
+	}
+	if m.filter.Focused() {
+		return m.legend() + "\n" + m.filterHint()
+	}
+	return m.legend() + "\n" + footerStyle.Render(hints)
+}
+
 func (m model) View() string {
-	return m.vp.View()
+	view := m.pinned() + m.vp.View() + "\n" + m.bottom()
+	if m.showHelp {
+		view = overlay(view, m.renderHelp(), m.termWidth)
+	}
+	if m.inline {
+		// Bubble Tea erases the cursor's line on exit; end on an empty one
+		// so the whole frame survives in the scrollback.
+		return view + "\n"
+	}
+	return view
 }
 
 func main() {
-	interval := flag.Duration("interval", 1*time.Second, "Update interval")
+	interval := flag.Duration("interval", 1*time.Second, "Update interval, from 100ms to 1m")
 	ignoreFlag := flag.String("ignore", "", "Comma-separated list of adaptors to ignore")
+	headers := flag.Bool("headers", false, "Label the columns in a line pinned above the rows ('L' toggles)")
+	noBars := flag.Bool("no-bars", false, "Show the rates and percentages as an aligned table without bars, for terminals that mangle block characters and for copying the display out as text")
+	minimal := flag.Bool("minimal", false, "Show only each port's name and a tall utilization bar with its percentage, green, yellow (70%) or red (-crit), for wall displays")
+	columnsFlag := flag.Int("columns", 1, "Lay the rows out in N columns side by side on wide terminals, or 0 for as many as fit; too narrow a terminal falls back to one")
+	fit := flag.Bool("fit", false, "Fit every port on screen without scrolling, as short lines of name, one combined bar and percentages, in columns if need be, for dashboards")
+	logScale := flag.Bool("log-scale", false, "Draw bars on a logarithmic scale from 0.01% to 100% of line rate, so light traffic still shows; figures are unchanged")
+	rxOnly := flag.Bool("rx-only", false, "Show only each port's RX bar, twice as wide; TX is still sampled ('v' cycles)")
+	txOnly := flag.Bool("tx-only", false, "Show only each port's TX bar, twice as wide; RX is still sampled ('v' cycles)")
+	pfOnly := flag.Bool("pf-only", false, "Monitor only physical functions, leaving out SR-IOV virtual functions (local ports)")
+	vfOnly := flag.Bool("vf-only", false, "Monitor only SR-IOV virtual functions, e.g. to watch tenant traffic (local ports)")
+	sysfsPath := flag.String("sysfs", ibmon.DefaultSysfsPath, "Sysfs directory containing InfiniBand adaptors")
+	hideIdle := flag.Bool("hide-idle", false, "Hide interfaces with no traffic (toggle with 'h')")
+	top := flag.Int("top", 0, "Show only the N busiest ports by RX+TX, busiest first; all are still sampled (cycle with 'T')")
+	base2 := flag.Bool("base2", false, "Display throughput in binary Gibit/s (2^30) instead of decimal Gbit/s")
+	rateBasis := flag.String("rate-basis", basisPerSecond, "Show rates per-second (Gb/s) or per-interval (Gb moved over each -interval); percentages stay per second")
+	tempWarn := flag.Float64("temp-warn", 70, "Module temperature (°C) above which the diagnostics panel shows red")
+	smooth := flag.Int("smooth", 1, "Average displayed values over the last N samples (1 disables smoothing)")
+	layout := flag.String("layout", layoutSplit, "Bar layout: split (separate RX/TX bars) or combined (one bar per port)")
+	rxCounter := flag.String("rx-counter", "", "File under counters/ to read RX from instead of port_rcv_data (*_data or *_packets; pair packets with -auto-units)")
+	txCounter := flag.String("tx-counter", "", "File under counters/ to read TX from instead of port_xmit_data (*_data or *_packets)")
+	counterUnit := flag.String("counter-unit", "", "Override what the data counters count: words (4 octets, as the InfiniBand spec has it) or bytes, for drivers whose port_rcv_data counts bytes (local ports)")
+	source := flag.String("source", ibmon.SourceIB, "Counters to read: ib (the port's data counters) or ipoib (the byte counters of each port's IPoIB netdev, IP traffic only; local ports)")
+	replayPath := flag.String("replay", "", "Play back a -logformat csv log instead of reading counters, one snapshot per recorded interval")
+	replaySpeed := flag.Float64("replay-speed", 1, "Speed up (>1) or slow down (<1) -replay relative to the recorded interval")
+	replayLoop := flag.Bool("replay-loop", false, "Start -replay over at the end instead of stopping")
+	demo := flag.Bool("demo", false, "Show simulated ports with generated traffic instead of reading sysfs, for demos and UI testing")
+	remoteFlag := flag.String("remote", "", "Comma-separated [user@]host[:port] list to monitor over SSH instead of local ports")
+	otlpEndpoint := flag.String("otlp", "", "Export OpenTelemetry metrics over OTLP/gRPC to host:port (needs a build with -tags otel)")
+	bell := flag.Bool("bell", false, "Ring the terminal bell when a port turns critical (see -crit)")
+	notify := flag.Bool("notify", false, "Run notify-send when a port turns critical (see -crit)")
+	flashFlag := flag.Bool("flash", false, "Briefly highlight each rate that changed by more than -flash-threshold since the last tick")
+	flashPct := flag.Float64("flash-threshold", 5, "Percent of line rate a rate must change by for -flash to highlight it")
+	critPct := flag.Float64("crit", 95, "Percent of line rate at which a port is critical for -bell and -notify; rising error counters always are (0 disables the rate check)")
+	alertDebounce := flag.Duration("alert-debounce", time.Minute, "Minimum time between -bell/-notify alerts for the same port")
+	statsdAddr := flag.String("statsd", "", "Send gauges to a StatsD/DogStatsD daemon at host:port")
+	plain := flag.Bool("plain", false, "Redraw a plain text table in place each interval instead of the TUI")
+	oneline := flag.Bool("oneline", false, "Rewrite one compact summary line per interval (for status bars) instead of the TUI")
+	onelineSep := flag.String("oneline-sep", "  ", "Separator between ports in -oneline output")
+	onelinePct := flag.Bool("oneline-pct", false, "Include percent of line rate in -oneline output")
+	jsonOut := flag.Bool("json", false, "Print newline-delimited JSON snapshots to stdout instead of the TUI")
+	grpcAddr := flag.String("grpc", "", "Serve a gRPC Subscribe stream of samples on this [host]:port, alongside any other output (needs a build with -tags grpc)")
+	httpAddr := flag.String("http", "", "Serve the current throughput as an HTML or plain-text table on this [host]:port at /, and the byte counts behind it at /raw, alongside any other output")
+	socketPath := flag.String("socket", "", "Run as a daemon streaming JSON snapshots to clients of this Unix socket")
+	fifoPath := flag.String("fifo", "", "Stream JSON snapshots into this existing named pipe (see mkfifo) whenever a reader has it open, alongside any other output")
+	autoUnits := flag.Bool("auto-units", false, "Format each rate in the most readable unit (bps to Tbps) instead of fixed Gbps")
+	precision := flag.Int("precision", 1, "Decimal places of displayed rates (0-6)")
+	rateRefresh := flag.Duration("rate-refresh", 10*time.Second, "How often to re-read each port's link rate, to follow links that renegotiate (0 disables)")
+	compactNumbers := flag.Bool("compact-numbers", false, "Right-align displayed rates with spaces instead of leading zeros, e.g. \"  12.3G\" for \"0012.3G\"")
+	avgWindow := flag.Duration("avg-window", 0, "Show the average throughput over this trailing window next to each rate (0 disables)")
+	adaptive := flag.Bool("adaptive", false, "Read idle ports less often: each idle read doubles the time to a port's next one, up to -adaptive-max, and any traffic brings it back to -interval")
+	adaptiveMax := flag.Duration("adaptive-max", defaultAdaptiveMax, "Longest time -adaptive leaves an idle port unread")
+	rtt := flag.Duration("rtt", 0, "Show each rate's bandwidth-delay product for this round-trip time, the bytes in flight if the current rate held for a whole round trip (0 disables)")
+	avgMarker := flag.Bool("avg-marker", false, "Mark the -avg-window average on each bar, over the current fill ('o' toggles)")
+	duration := flag.Duration("duration", 0, "Quit after this long and print a peak/average summary (exclusive with -count)")
+	count := flag.Int("count", 0, "Quit after this many samples and print a peak/average summary (exclusive with -duration)")
+	barCharsFlag := flag.String("bar-chars", "blocks", "Characters to draw bars with: blocks, shade, ascii, or two characters for the fill and the empty part (e.g. \"=.\")")
+	noAnimation := flag.Bool("no-animation", false, "Move the bars straight to each new value instead of animating them (for slow terminals)")
+	noAltScreen := flag.Bool("no-altscreen", false, "Draw in the normal terminal buffer instead of the alternate screen, leaving the last frame in scrollback on quit")
+	noSummary := flag.Bool("no-summary", false, "Don't print the peak/average/total summary on quit (it is still printed after -count or -duration)")
+	failOnDown := flag.Bool("fail-on-down", false, "With -count or -duration, exit with status 1 if any port was not ACTIVE during the run")
+	var aggGroups aggGroupFlags
+	flag.Var(&aggGroups, "group", "Aggregate ports into a summed row, as name=mlx5_0:1+mlx5_1:1 (repeatable; 'm' shows members)")
+	verbose := flag.Bool("verbose", false, "Log each adaptor or port skipped during discovery, and why, and the driver profile each port was found with")
+	wait := flag.Duration("wait", 0, "Keep retrying discovery for up to this long if no interfaces are found (0 fails immediately)")
+	showNetdev := flag.Bool("show-netdev", false, "Show each port's IPoIB network interface (e.g. ib0) next to its name")
+	showModel := flag.Bool("show-model", false, "Show each port's adaptor model (e.g. ConnectX-6 Dx) next to its name, for nodes with mixed hardware")
+	raw := flag.Bool("raw", false, "Show each port's raw counter values and their last change under its row ('x' toggles)")
+	noRowColors := flag.Bool("no-row-colors", false, "Draw every row header in the default color instead of one color per port")
+	graphPath := flag.String("graph", "", "On exit, chart RX/TX history to this .png or .svg file")
+	graphOverlay := flag.Bool("graph-overlay", false, "Draw all interfaces on one -graph chart instead of one chart each")
+	get := flag.String("get", "", "Print one raw counter, as adaptor:port:rx or adaptor:port:tx (append :rate for its rate over -interval), and exit")
+	selftest := flag.Bool("selftest", false, "Check that the sysfs counters and rate files can be read, print PASS or FAIL with hints, and exit (nonzero on FAIL)")
+	showVersion := flag.Bool("version", false, "Print the version, commit and build date, and exit")
+	list := flag.Bool("list", false, "Print the discovered interfaces (as JSON with -json) and exit")
+	summaryJSON := flag.String("summary-json", "", "On exit, however it comes about, write each port's averages, peaks, totals and error counter deltas with run metadata to this JSON file")
+	statePath := flag.String("state", "", "Keep peaks, totals and histograms in this JSON file, restoring them at startup and saving them periodically and on exit")
+	logPath := flag.String("logfile", "", "Append every snapshot to this file (see -logformat, -logmax, -logkeep)")
+	logFormat := flag.String("logformat", logFormatJSON, "Format of -logfile records: json (JSON Lines) or csv")
+	logMax := flag.String("logmax", "100MB", "Rotate -logfile once it would exceed this size (0 disables rotation)")
+	logKeep := flag.Int("logkeep", 5, "Number of rotated -logfile files to keep")
+	auditPath := flag.String("audit", "", "Append each displayed frame, as timestamped plain text, to this file whenever it changes")
+	configPath := flag.String("config", "", "YAML config file with flag defaults (default $XDG_CONFIG_HOME/ibmon/config.yaml)")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "Usage of %s:\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprintf(out, "\nEvery flag can also be set with an environment variable, e.g. %s for -interval\n"+
+			"or %s for -hide-idle. A flag given on the command line takes precedence over\n"+
+			"the environment, which takes precedence over the config file and then the default.\n",
+			envName("interval"), envName("hide-idle"))
+	}
 	flag.Parse()
+	if *showVersion {
+		fmt.Println(versionString())
+		return
+	}
+
+	// Environment variables, then config-file values, fill in any flags not
+	// given on the command line.
+	if err := loadEnv(flag.CommandLine, os.Environ()); err != nil {
+		log.Fatal(err)
+	}
+	if *configPath != "" {
+		if err := loadConfig(flag.CommandLine, *configPath, true); err != nil {
+			log.Fatal(err)
+		}
+	} else if path := defaultConfigPath(); path != "" {
+		if err := loadConfig(flag.CommandLine, path, false); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *flashPct < 0 {
+		log.Fatalf("invalid -flash-threshold %v: must not be negative", *flashPct)
+	}
+	if err := validateInterval(*interval); err != nil {
+		log.Fatal(err)
+	}
+	if *source != ibmon.SourceIB && *source != ibmon.SourceIPoIB {
+		log.Fatalf("invalid -source %q: must be %q or %q", *source, ibmon.SourceIB, ibmon.SourceIPoIB)
+	}
+	if *layout != layoutSplit && *layout != layoutCombined {
+		log.Fatalf("invalid -layout %q: must be %q or %q", *layout, layoutSplit, layoutCombined)
+	}
+	if *duration > 0 && *count > 0 {
+		log.Fatal("-duration and -count are mutually exclusive")
+	}
+	chars, err := parseBarChars(*barCharsFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	columns := *columnsFlag
+	switch {
+	case columns < 0:
+		log.Fatalf("invalid -columns %d: must not be negative", columns)
+	case columns == 0:
+		columns = columnsAuto
+	}
+	if *fit && *minimal {
+		log.Fatal("-fit and -minimal are mutually exclusive")
+	}
+	if *noBars && (*minimal || *fit) {
+		log.Fatal("-no-bars cannot be combined with -minimal or -fit")
+	}
+	if *rxOnly && *txOnly {
+		log.Fatal("-rx-only and -tx-only are mutually exclusive")
+	}
+	direction := dirBoth
+	switch {
+	case *rxOnly:
+		direction = dirRX
+	case *txOnly:
+		direction = dirTX
+	}
+	if *adaptive && *adaptiveMax < *interval {
+		log.Fatalf("invalid -adaptive-max %v: must be at least -interval (%v)", *adaptiveMax, *interval)
+	}
+	if *rtt < 0 {
+		log.Fatalf("invalid -rtt %v: must not be negative", *rtt)
+	}
+	if *avgMarker && *avgWindow <= 0 {
+		log.Fatal("-avg-marker needs -avg-window")
+	}
+	if *pfOnly && *vfOnly {
+		log.Fatal("-pf-only and -vf-only are mutually exclusive")
+	}
+	if *failOnDown && *duration == 0 && *count == 0 {
+		log.Fatal("-fail-on-down needs -count or -duration")
+	}
+	if *failOnDown && (*remoteFlag != "" || *replayPath != "") {
+		log.Fatal("-fail-on-down only checks local ports")
+	}
+	if *rateBasis != basisPerSecond && *rateBasis != basisPerInterval {
+		log.Fatalf("invalid -rate-basis %q: must be %q or %q", *rateBasis, basisPerSecond, basisPerInterval)
+	}
+	if *top < 0 {
+		log.Fatalf("invalid -top %d: must not be negative", *top)
+	}
+	if *precision < 0 || *precision > maxPrecision {
+		log.Fatalf("invalid -precision %d: must be 0 to %d", *precision, maxPrecision)
+	}
+	var target getTarget
+	if *get != "" {
+		t, err := parseGetTarget(*get)
+		if err != nil {
+			log.Fatal(err)
+		}
+		target = t
+	}
 	ignoreMap := make(map[string]bool)
 	if *ignoreFlag != "" {
 		for _, name := range strings.Split(*ignoreFlag, ",") {
@@ -320,14 +1895,362 @@ func main() {
 		}
 	}
 
-	m, err := initialModel(*interval, ignoreMap)
+	var onSkip func(string, error)
+	if *verbose {
+		onSkip = func(name string, reason error) {
+			log.Printf("skipped %s: %v", name, reason)
+		}
+	}
+
+	opts := options{
+		interval: *interval,
+		discover: ibmon.Options{
+			SysfsPath:   *sysfsPath,
+			Ignore:      ignoreMap,
+			RxCounter:   *rxCounter,
+			TxCounter:   *txCounter,
+			Source:      *source,
+			CounterUnit: *counterUnit,
+			PFOnly:      *pfOnly,
+			VFOnly:      *vfOnly,
+			OnSkip:      onSkip,
+		},
+		hideIdle:       *hideIdle,
+		top:            *top,
+		base2:          *base2,
+		perInterval:    *rateBasis == basisPerInterval,
+		tempWarn:       *tempWarn,
+		critPct:        *critPct,
+		flash:          *flashFlag,
+		flashPct:       *flashPct,
+		minimal:        *minimal,
+		noBars:         *noBars,
+		fit:            *fit,
+		columns:        columns,
+		logScale:       *logScale,
+		rtt:            *rtt,
+		adaptive:       *adaptive,
+		adaptiveMax:    *adaptiveMax,
+		showHeaders:    *headers,
+		smooth:         *smooth,
+		avgWindow:      *avgWindow,
+		avgMarker:      *avgMarker,
+		autoUnits:      *autoUnits,
+		precision:      *precision,
+		compactNumbers: *compactNumbers,
+		rateRefresh:    *rateRefresh,
+		layout:         *layout,
+		direction:      direction,
+		count:          *count,
+		duration:       *duration,
+		aggGroups:      aggGroups,
+		wait:           *wait,
+		showNetdev:     *showNetdev,
+		showModel:      *showModel,
+		rowColors:      !*noRowColors,
+		raw:            *raw,
+		failOnDown:     *failOnDown,
+		summaryOnQuit:  !*noSummary,
+		inline:         *noAltScreen,
+		animate:        !*noAnimation,
+		barChars:       chars,
+	}
+	if *graphPath != "" {
+		// -graph keeps its own history, independent of -smooth and
+		// -avg-window.
+		opts.graphSpan = defaultGraphSpan
+	}
+	if *jsonOut {
+		opts.emitters = append(opts.emitters, newJSONEmitter(os.Stdout))
+	}
+	if *statsdAddr != "" {
+		c, err := newStatsdClient(*statsdAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer c.Close()
+		opts.emitters = append(opts.emitters, c)
+	}
+	if *otlpEndpoint != "" {
+		exp, err := newOtelExporter(*otlpEndpoint, *interval)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer exp.Shutdown()
+		opts.emitters = append(opts.emitters, exp)
+	}
+	if *bell || *notify {
+		var bellOut io.Writer
+		if *bell {
+			// stderr, so the bell reaches the terminal even in the
+			// stdout-based modes and without disturbing the TUI's output.
+			bellOut = os.Stderr
+		}
+		a, err := newAlerter(bellOut, *notify, *critPct, *alertDebounce)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.alerts = a
+		opts.emitters = append(opts.emitters, a)
+	}
+	if *httpAddr != "" {
+		srv, err := newHTTPServer(*httpAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer srv.Close()
+		opts.emitters = append(opts.emitters, srv)
+	}
+	if *grpcAddr != "" {
+		srv, err := newGRPCServer(*grpcAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer srv.Close()
+		opts.emitters = append(opts.emitters, srv)
+	}
+	if *socketPath != "" {
+		srv, err := newSocketServer(*socketPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer srv.Close()
+		opts.emitters = append(opts.emitters, srv)
+	}
+	if *fifoPath != "" {
+		f, err := newFifoSink(*fifoPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		opts.emitters = append(opts.emitters, f)
+	}
+	if *logPath != "" {
+		maxSize, err := parseSize(*logMax)
+		if err != nil {
+			log.Fatalf("-logmax: %v", err)
+		}
+		l, err := newLogSink(*logPath, *logFormat, maxSize, *logKeep)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer l.Close()
+		opts.emitters = append(opts.emitters, l)
+	}
+	if *auditPath != "" {
+		a, err := newAuditLog(*auditPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer a.Close()
+		opts.audit = a
+	}
+	if *statePath != "" {
+		opts.state = &stateFile{path: *statePath}
+	}
+	opts.summaryJSON = *summaryJSON
+	if *replayPath != "" {
+		if *remoteFlag != "" {
+			log.Fatal("-replay and -remote are mutually exclusive")
+		}
+		if *replaySpeed <= 0 {
+			log.Fatalf("invalid -replay-speed %v: must be positive", *replaySpeed)
+		}
+		r, err := loadReplay(*replayPath, *replayLoop)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if d := r.interval(); d > 0 {
+			if opts.interval, err = replayInterval(d, *replaySpeed); err != nil {
+				log.Fatal(err)
+			}
+		}
+		opts.replay = r
+	}
+	if *demo {
+		if *replayPath != "" || *remoteFlag != "" {
+			log.Fatal("-demo cannot be combined with -replay or -remote")
+		}
+		opts.replay = newDemo(*interval)
+	}
+	if *remoteFlag != "" {
+		for _, target := range strings.Split(*remoteFlag, ",") {
+			host, err := newRemoteHost(strings.TrimSpace(target), *sysfsPath, *interval)
+			if err != nil {
+				log.Fatal(err)
+			}
+			opts.remotes = append(opts.remotes, host)
+		}
+	}
+
+	if *selftest {
+		if !runSelftest(os.Stdout, opts.discover) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// The TUI comes up straight away and discovers the ports behind a
+	// spinner, so a slow sysfs does not leave a blank terminal. -verbose
+	// discovers first, as its log lines would scribble over the TUI.
+	tui := !*list && *get == "" && !*plain && !*oneline && !*jsonOut && *socketPath == ""
+	var m model
+	if tui && !*verbose {
+		m = newDiscoveringModel(opts)
+	} else {
+		var err error
+		if m, err = initialModel(opts); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *verbose {
+		for _, stat := range m.statuses {
+			if stat.iface.Profile != "" {
+				msg := fmt.Sprintf("found %s: %s profile, %s/", stat.name(), stat.iface.Profile, stat.iface.CounterSource)
+				if ids := portIDs(stat.iface); ids != "" {
+					msg += ", " + ids
+				}
+				log.Print(msg)
+			}
+		}
+	}
+
+	if *list {
+		if err := m.writeList(os.Stdout, *jsonOut); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if *get != "" {
+		if err := m.runGet(os.Stdout, target); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Text and snapshot outputs run without the TUI.
+	if *plain {
+		final, err := runPlain(m, os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		finish(final, *graphPath, *graphOverlay)
+		return
+	}
+	if *oneline {
+		final, err := runOneline(m, os.Stdout, *onelineSep, *onelinePct)
+		if err != nil {
+			log.Fatal(err)
+		}
+		finish(final, *graphPath, *graphOverlay)
+		return
+	}
+	if *jsonOut || *socketPath != "" {
+		// No summary here: stdout is a JSON stream.
+		final, err := runHeadless(m, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		saveState(final)
+		saveRunSummary(final)
+		if *graphPath != "" {
+			if err := final.writeGraph(*graphPath, *graphOverlay); err != nil {
+				log.Fatal(err)
+			}
+		}
+		checkLinks(final)
+		return
+	}
+
+	// Lay out for the terminal until it reports its size, in case it never
+	// does.
+	width, height, ok := initialSize()
+	if !ok && *verbose {
+		log.Printf("terminal size unknown, assuming %dx%d until the terminal reports one", width, height)
+	}
+	m.resize(width, height)
+
+	// Use the alternate screen unless -no-altscreen asks for the normal one.
+	progOpts := []tea.ProgramOption{tea.WithMouseCellMotion(), tea.WithoutSignalHandler()}
+	if !m.inline {
+		progOpts = append(progOpts, tea.WithAltScreen())
+	}
+	p := tea.NewProgram(m, progOpts...)
+	go forwardSignals(p)
+	final, err := p.Run()
 	if err != nil {
 		log.Fatal(err)
 	}
+	fm := final.(model)
+	if fm.discoverErr != nil {
+		log.Fatal(fm.discoverErr)
+	}
+	if fm.discovering {
+		// Quit before any port was found: there is nothing to report.
+		return
+	}
+	finish(fm, *graphPath, *graphOverlay)
+}
+
+// finish runs the end-of-run outputs of the interactive and text modes: the
+// -count/-duration summary, the -state file and the -graph chart, if
+// requested, then the -fail-on-down check.
+func finish(m model, graphPath string, overlay bool) {
+	printSummary(m)
+	saveState(m)
+	saveRunSummary(m)
+	if graphPath != "" {
+		if err := m.writeGraph(graphPath, overlay); err != nil {
+			log.Fatal(err)
+		}
+	}
+	checkLinks(m)
+}
+
+// saveRunSummary writes the -summary-json file, if one was asked for.
+func saveRunSummary(m model) {
+	if m.summaryJSON == "" {
+		return
+	}
+	if err := m.writeRunSummary(m.summaryJSON, time.Now()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// saveState writes the -state file a final time, if one is kept.
+func saveState(m model) {
+	if m.state == nil {
+		return
+	}
+	if err := m.state.save(m.statuses, time.Now()); err != nil {
+		log.Fatal(err)
+	}
+}
 
-	// Use the alternate screen; remove tea.WithAltScreen() if you prefer the normal terminal.
-	p := tea.NewProgram(m, tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+// checkLinks exits with status 1, naming each port and the state it was
+// found in, if -fail-on-down saw any port that was not ACTIVE.
+func checkLinks(m model) {
+	if !m.failOnDown {
+		return
+	}
+	var down []string
+	for _, stat := range m.statuses {
+		if stat.downState != "" {
+			down = append(down, stat.name()+" ("+stat.downState+")")
+		}
+	}
+	if len(down) > 0 {
+		log.Fatalf("ports not ACTIVE during the run: %s", strings.Join(down, ", "))
+	}
+}
+
+// printSummary writes the run summary to stdout once a -count or -duration
+// limit has stopped monitoring, or on quitting unless -no-summary is set.
+func printSummary(m model) {
+	if m.maxTicks == 0 && m.maxDuration == 0 && !m.summaryOnQuit {
+		return
+	}
+	if err := m.writeSummary(os.Stdout); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apsu/ibmon/ibmon"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestDisplayUnits(t *testing.T) {
+	tests := []struct {
+		gbps  float64
+		base2 bool
+		want  float64
+	}{
+		{gbps: 100, base2: false, want: 100},
+		{gbps: 1.073741824, base2: true, want: 1},
+		{gbps: 400, base2: true, want: 400e9 / (1 << 30)},
+	}
+	for _, tt := range tests {
+		if got := displayUnits(tt.gbps, tt.base2); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("displayUnits(%v, %v) = %v, want %v", tt.gbps, tt.base2, got, tt.want)
+		}
+	}
+}
+
+func TestAutoRate(t *testing.T) {
+	tests := []struct {
+		gbps  float64
+		base2 bool
+		want  string
+	}{
+		{gbps: 0, want: "0 bps"},
+		{gbps: 0.003, want: "3.00 Mbps"},
+		{gbps: 12.34, want: "12.3 Gbps"},
+		{gbps: 400, want: "400 Gbps"},
+		{gbps: 999.9, want: "1.00 Tbps"},
+		{gbps: 1600, want: "1.60 Tbps"},
+		{gbps: 1.073741824, base2: true, want: "1.00 Gibps"},
+		{gbps: 0.000001, want: "1.00 Kbps"},
+	}
+	for _, tt := range tests {
+		if got := autoRate(tt.gbps, tt.base2); got != tt.want {
+			t.Errorf("autoRate(%v, %v) = %q, want %q", tt.gbps, tt.base2, got, tt.want)
+		}
+	}
+}
+
+func TestFormatRatePrecision(t *testing.T) {
+	tests := []struct {
+		precision int
+		compact   bool
+		want      string
+	}{
+		{precision: 0, want: "0012G"},
+		{precision: 1, want: "0012.3G"},
+		{precision: 3, want: "0012.346G"},
+		{precision: 1, compact: true, want: "  12.3G"},
+		{precision: 0, compact: true, want: "  12G"},
+	}
+	for _, tt := range tests {
+		m := model{precision: tt.precision, compactNumbers: tt.compact}
+		got := m.formatRate(12.3456)
+		if got != tt.want {
+			t.Errorf("precision %d, compact %v: formatRate = %q, want %q", tt.precision, tt.compact, got, tt.want)
+		}
+		if len(got) != m.rateWidth() {
+			t.Errorf("precision %d, compact %v: len(%q) = %d, rateWidth = %d", tt.precision, tt.compact, got, len(got), m.rateWidth())
+		}
+	}
+}
+
+func TestRenderBothBases(t *testing.T) {
+	// The percent of line rate must not depend on the display base: it is
+	// always computed from decimal Gbps against the decimal link rate.
+	tests := []struct {
+		base2 bool
+		value string
+	}{
+		{base2: false, value: "0200.0G "},
+		{base2: true, value: "0186.3Gi "},
+	}
+	for _, tt := range tests {
+		m := model{
+			termWidth: 120,
+			precision: 1,
+			base2:     tt.base2,
+			statuses: []ifaceStatus{{
+				iface:   ibmon.Interface{Adaptor: "mlx5_0", Port: "1", MaxGbps: 400},
+				rxValue: 200,
+			}},
+		}
+		out := m.renderContent()
+		if !strings.Contains(out, "  50% "+tt.value) {
+			t.Errorf("base2=%v: want 50%% and %q in row, got %q", tt.base2, tt.value, out)
+		}
+	}
+}
+
+func TestRenderDirectionArrows(t *testing.T) {
+	// Down is receive and up is transmit: each arrow must lead its own
+	// direction's figures in every layout.
+	const header = "mlx5_0:1   (400G):" // fixed 18-character field
+	tests := []struct {
+		layout     string
+		termWidth  int
+		rxPrefix   string // rendered RX part, right after the header
+		txContains string
+	}{
+		{layout: layoutSplit, termWidth: 120, rxPrefix: "↓ ", txContains: "0200.0G   ↑ "},
+		{layout: layoutCombined, termWidth: 120, rxPrefix: "↓ 0200.0G   50% ", txContains: "0000.0G ↑"},
+	}
+	for _, tt := range tests {
+		m := model{
+			termWidth: tt.termWidth,
+			precision: 1,
+			layout:    tt.layout,
+			selected:  -1,
+			statuses: []ifaceStatus{{
+				iface:   ibmon.Interface{Adaptor: "mlx5_0", Port: "1", MaxGbps: 400},
+				rxValue: 200,
+			}},
+		}
+		out := m.renderContent()
+		if !strings.HasPrefix(out, header+tt.rxPrefix) {
+			t.Errorf("%s: want row to start with %q, got %q", tt.layout, header+tt.rxPrefix, out)
+		}
+		if !strings.Contains(out, tt.txContains) {
+			t.Errorf("%s: want %q in row, got %q", tt.layout, tt.txContains, out)
+		}
+	}
+
+	rows := strings.Split(compactRows("mlx5_0:1", 40, 0.5, 0, defaultBarChars, false), "\n")
+	if !strings.HasPrefix(rows[0], "mlx5_0:1   ↓ ") || !strings.HasPrefix(rows[1], strings.Repeat(" ", 10)+" ↑ ") {
+		t.Errorf("compact rows: want RX on ↓ then TX on ↑, got %q", rows)
+	}
+}
+
+func TestRateRefresh(t *testing.T) {
+	// A port discovered at 400G that renegotiates down to 100G: the same
+	// 40 Gbps reads 10% of line rate before and 40% after.
+	root := t.TempDir()
+	port := filepath.Join(root, "mlx5_0", "ports", "1")
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(port, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(port, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("rate", "400 Gb/sec (4X NDR)\n")
+	write("counters/port_xmit_data", "0\n")
+	write("counters/port_rcv_data", "0\n")
+
+	m, err := initialModel(options{
+		interval:    time.Second,
+		discover:    ibmon.Options{SysfsPath: root},
+		precision:   1,
+		rateRefresh: time.Nanosecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.termWidth = 120
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ibmon.Clock = func() time.Time { return now }
+	defer func() { ibmon.Clock = time.Now }()
+
+	// 1.25e9 4-byte words per 1s interval is 40 Gbps.
+	write("counters/port_rcv_data", "1250000000\n")
+	m.sample()
+	if out := m.renderContent(); !strings.Contains(out, "(400G)") || !strings.Contains(out, "  10% 0040.0G") {
+		t.Fatalf("at 400G: want 10%% of line rate, got %q", out)
+	}
+
+	// The next read comes 2s later, a tick handled late: Sample measures
+	// the same 1.25e9 words over the 2s that passed, 20 Gbps, which reads
+	// 20% of the renegotiated 100G.
+	now = now.Add(2 * time.Second)
+	write("rate", "100 Gb/sec (4X EDR)\n")
+	write("counters/port_rcv_data", "2500000000\n")
+	m.sample()
+	if out := m.renderContent(); !strings.Contains(out, "(100G)") || !strings.Contains(out, "  20% 0020.0G") {
+		t.Errorf("after renegotiating to 100G: want 20%% of line rate over 2s, got %q", out)
+	}
+	if stat := m.statuses[0]; !rateChanged(stat) || stat.iface.Rate != "100 Gb/sec (4X EDR)" {
+		t.Errorf("after renegotiating: rate %q, highlighted %v; want the new rate, highlighted", stat.iface.Rate, rateChanged(stat))
+	}
+}
+
+func TestNextTick(t *testing.T) {
+	anchor := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return anchor.Add(d) }
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"at the anchor", at(0), at(time.Second)},
+		{"before the anchor", at(-time.Second), at(time.Second)},
+		{"handled late", at(3*time.Millisecond + 1), at(time.Second)},
+		{"on a tick time", at(5 * time.Second), at(6 * time.Second)},
+		// A tick that arrives after the next target skips it, rather than
+		// firing at once and again on the grid.
+		{"target missed", at(2500 * time.Millisecond), at(3 * time.Second)},
+		// After hours the targets are still whole seconds from the anchor,
+		// whatever each tick took to handle.
+		{"no drift", at(10*time.Hour + 999*time.Millisecond), at(10*time.Hour + time.Second)},
+	}
+	for _, tt := range tests {
+		if got := nextTick(anchor, time.Second, tt.now); !got.Equal(tt.want) {
+			t.Errorf("%s: nextTick at +%v = +%v, want +%v", tt.name, tt.now.Sub(anchor), got.Sub(anchor), tt.want.Sub(anchor))
+		}
+	}
+}
+
+func TestValidateInterval(t *testing.T) {
+	tests := []struct {
+		d       time.Duration
+		wantErr bool
+	}{
+		{0, true},
+		{-time.Second, true},
+		{50 * time.Millisecond, true},
+		{minInterval, false},
+		{time.Second, false},
+		{maxInterval, false},
+		{time.Hour, true},
+	}
+	for _, tt := range tests {
+		if err := validateInterval(tt.d); (err != nil) != tt.wantErr {
+			t.Errorf("validateInterval(%v) = %v, want error %v", tt.d, err, tt.wantErr)
+		}
+	}
+}
+
+func TestBarPosition(t *testing.T) {
+	tests := []struct {
+		frac     float64
+		logScale bool
+		want     float64
+	}{
+		{0.3, false, 0.3},
+		{0, true, 0},
+		{logScaleFloor / 2, true, 0},
+		{0.001, true, 0.25},
+		{0.01, true, 0.5},
+		{0.1, true, 0.75},
+		{1, true, 1},
+	}
+	for _, tt := range tests {
+		if got := barPosition(tt.frac, tt.logScale); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("barPosition(%v, %v) = %v, want %v", tt.frac, tt.logScale, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDelta(t *testing.T) {
+	m := model{precision: 1, baseline: map[string]baselineRates{}}
+	tests := []struct {
+		gbps float64
+		want string
+	}{
+		{12.3, " Δ+0012.3"},
+		{-4.1, " Δ-0004.1"},
+		{0.01, " Δ 0000.0"},
+	}
+	for _, tt := range tests {
+		got := stripANSI(m.formatDelta(tt.gbps))
+		if got != tt.want {
+			t.Errorf("formatDelta(%v) = %q, want %q", tt.gbps, got, tt.want)
+		}
+		if w := lipgloss.Width(got); w != m.baselineWidth() {
+			t.Errorf("formatDelta(%v) is %d wide, baselineWidth = %d", tt.gbps, w, m.baselineWidth())
+		}
+	}
+}
+
+func TestRenderHelpFits(t *testing.T) {
+	for _, width := range []int{140, 120, 80} {
+		m := model{termWidth: width, filter: newFilterInput(), interval: time.Second}
+		if got := lipgloss.Width(m.renderHelp()); got > width {
+			t.Errorf("help panel is %d wide on a %d-column terminal", got, width)
+		}
+	}
+}
+
+func BenchmarkSample32Ports(b *testing.B) {
+	// Four HCAs of eight ports each, with every counter a sample reads,
+	// error counters for alerts included: the budget for a tick is the 200ms of the shortest practical -interval.
+	root := b.TempDir()
+	write := func(name, content string) {
+		b.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0o755); err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	for dev := 0; dev < 4; dev++ {
+		for port := 1; port <= 8; port++ {
+			dir := filepath.Join(fmt.Sprintf("mlx5_%d", dev), "ports", fmt.Sprint(port))
+			write(filepath.Join(dir, "rate"), "400 Gb/sec (4X NDR)\n")
+			write(filepath.Join(dir, "state"), "4: ACTIVE\n")
+			for _, c := range []string{"port_xmit_data", "port_rcv_data", "port_xmit_wait"} {
+				write(filepath.Join(dir, ibmon.CountersStd, c), "1250000000\n")
+			}
+			for _, c := range []string{"np_ecn_marked_roce_packets", "np_cnp_sent", "rp_cnp_handled"} {
+				write(filepath.Join(dir, ibmon.CountersHW, c), "0\n")
+			}
+			for _, c := range ibmon.ErrorCounterNames {
+				write(filepath.Join(dir, ibmon.CountersStd, c), "0\n")
+			}
+		}
+	}
+
+	const interval = 200 * time.Millisecond
+	alerts, err := newAlerter(nil, false, 90, time.Minute)
+	if err != nil {
+		b.Fatal(err)
+	}
+	m, err := initialModel(options{
+		interval:   interval,
+		discover:   ibmon.Options{SysfsPath: root},
+		precision:  1,
+		raw:        true,
+		failOnDown: true,
+		alerts:     alerts,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	if len(m.statuses) != 32 {
+		b.Fatalf("discovered %d ports, want 32", len(m.statuses))
+	}
+	b.ResetTimer()
+	start := time.Now()
+	for range b.N {
+		m.sample()
+	}
+	if per := time.Since(start) / time.Duration(b.N); per > interval {
+		b.Fatalf("a tick of 32 ports takes %v, more than the %v interval", per, interval)
+	}
+}
+
+func TestRowPaletteExcludesStatusColors(t *testing.T) {
+	for _, c := range rowPalette {
+		switch c {
+		case okColor, warnColor, critColor:
+			t.Errorf("rowPalette has status color %s", c)
+		}
+	}
+}
+
+func TestDetailsLinkStateCached(t *testing.T) {
+	root := t.TempDir()
+	port := filepath.Join(root, "mlx5_0", "ports", "1")
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(port, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(port, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("rate", "400 Gb/sec (4X NDR)\n")
+	write("state", "4: ACTIVE\n")
+	write("counters/port_xmit_data", "0\n")
+	write("counters/port_rcv_data", "0\n")
+
+	m, err := initialModel(options{interval: time.Second, discover: ibmon.Options{SysfsPath: root}, precision: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.termWidth = 120
+	m.moveSelection(1)
+	if out := m.renderDetails(); !strings.Contains(out, "ACTIVE") {
+		t.Fatalf("details after selecting: want ACTIVE, got %q", out)
+	}
+
+	// Rendering shows the state read on the last tick, not sysfs as it is.
+	write("state", "1: DOWN\n")
+	if out := m.renderDetails(); !strings.Contains(out, "ACTIVE") {
+		t.Errorf("details read the link state while rendering: %q", out)
+	}
+	m.readSelected()
+	if out := m.renderDetails(); !strings.Contains(out, "DOWN") {
+		t.Errorf("details after a tick: want DOWN, got %q", out)
+	}
+}
+
+func TestReplayInterval(t *testing.T) {
+	tests := []struct {
+		recorded time.Duration
+		speed    float64
+		want     time.Duration // 0 if rejected
+	}{
+		{time.Second, 1, time.Second},
+		{time.Second, 4, 250 * time.Millisecond},
+		{time.Second, 100, 0},  // a 10ms tick, below minInterval
+		{time.Second, 0.01, 0}, // 100s, above maxInterval
+	}
+	for _, tt := range tests {
+		got, err := replayInterval(tt.recorded, tt.speed)
+		if got != tt.want || (err != nil) != (tt.want == 0) {
+			t.Errorf("replayInterval(%v, %v) = %v, %v; want %v", tt.recorded, tt.speed, got, err, tt.want)
+		}
+	}
+}
+
+func TestRebaseClearsHistory(t *testing.T) {
+	s := ifaceStatus{rxPeak: 10, samples: 2}
+	now := time.Now()
+	s.window.add(now, 10, 5)
+	s.history.add(now, 10, 5)
+	s.rebase()
+	if len(s.window.samples) != 0 || len(s.history.samples) != 0 || s.rxPeak != 0 || s.samples != 0 {
+		t.Errorf("after rebase: %d window and %d -graph samples, peak %v, %d samples; want all cleared",
+			len(s.window.samples), len(s.history.samples), s.rxPeak, s.samples)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// minimalWarnPct is the utilization, in percent of line rate, from which a
+// -minimal row turns yellow; from the -crit percentage on it is red.
+const minimalWarnPct = 70
+
+// Colors of a -minimal row by utilization.
+const (
+	minimalOK   = string(okColor)
+	minimalWarn = string(warnColor)
+	minimalCrit = string(critColor)
+)
+
+// minimalColor returns the color of a -minimal row at pct percent of line
+// rate.
+func (m model) minimalColor(pct float64) string {
+	switch {
+	case m.critPct > 0 && pct >= m.critPct:
+		return minimalCrit
+	case pct >= minimalWarnPct:
+		return minimalWarn
+	}
+	return minimalOK
+}
+
+// renderMinimal renders a port's -minimal row, meant to be read from across
+// the room: the port name and a bar two lines tall of the utilization of its
+// busier direction, with the percentage in bold, all in green, yellow or red
+// by utilization. There are no rates or arrows.
+//
+//	mlx5_0:1   ██████████████░░░░░░░░  64%
+//	           ██████████████░░░░░░░░
+func (m model) renderMinimal(label string, stat ifaceStatus, hostWidth int, selected bool) string {
+	const fixed = 17 // name (10) + " " (1) + " " (1) + percent (5)
+	rxValue, txValue := stat.displayValues()
+	pct := max(lineFraction(rxValue, stat.iface.MaxGbps), lineFraction(txValue, stat.iface.MaxGbps))
+	color := m.minimalColor(pct * 100)
+
+	bar := progress.New(progress.WithSolidFill(color), progress.WithoutPercentage(),
+		progress.WithWidth(max(5, m.termWidth-hostWidth-fixed)), m.barChars.option())
+	barView := bar.ViewAs(barPosition(pct, m.logScale))
+
+	name := fmt.Sprintf("%-10.10s", label)
+	if selected {
+		name = selectedStyle.Render(name)
+	}
+	pctStr := lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Bold(true).Render(fmt.Sprintf("%4d%%", int(pct*100)))
+	indent := strings.Repeat(" ", hostWidth+10)
+	return fmt.Sprintf("%s %s %s\n%s %s", name, barView, pctStr, indent, barView)
+}
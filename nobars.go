@@ -0,0 +1,44 @@
+package main
+
+import "fmt"
+
+// noBarsPctWidth is the width of a percentage with -no-bars, which gives
+// the room of the bars to a decimal place, e.g. " 45.3%".
+const noBarsPctWidth = 7
+
+// formatPctPrecise formats a fraction of line rate for -no-bars.
+func formatPctPrecise(frac float64) string {
+	return fmt.Sprintf("%*.1f%%", noBarsPctWidth-1, frac*100)
+}
+
+// renderNoBars lays out the figures of a row without bars, for -no-bars:
+// after the header, each shown direction's arrow, percentage and rate
+// field, in fixed-width columns that stay aligned when copied out as text.
+// The arrows stand in for the bars' direction, so no block characters are
+// drawn.
+func (m model) renderNoBars(rxPct, txPct float64, rxVal, txVal string) string {
+	rx := fmt.Sprintf("%s %s  %s", rxArrow(), formatPctPrecise(rxPct), rxVal)
+	tx := fmt.Sprintf("%s %s  %s", txArrow(), formatPctPrecise(txPct), txVal)
+	switch m.direction {
+	case dirRX:
+		return rx
+	case dirTX:
+		return tx
+	}
+	return rx + "   " + tx
+}
+
+// noBarsHeaders returns the column labels over the figures of renderNoBars,
+// given the label of one direction's rate field.
+func (m model) noBarsHeaders(rate func(dir string) string) string {
+	col := func(dir string) string {
+		return "  " + alignRight(dir+"%", noBarsPctWidth) + "  " + rate(dir)
+	}
+	switch m.direction {
+	case dirRX:
+		return col("RX")
+	case dirTX:
+		return col("TX")
+	}
+	return col("RX") + "   " + col("TX")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// runOneline rewrites a single summary line in place every interval, for
+// status bars such as tmux or i3bar. Each line is written with one call so
+// the reader sees it at once; a newline is added on exit.
+func runOneline(m model, w io.Writer, sep string, showPct bool) (model, error) {
+	prevWidth := 0
+	final, err := runHeadless(m, func(m model) error {
+		line := m.renderOneline(sep, showPct)
+		// Pad over the tail of a longer previous line, in terminal cells:
+		// the arrows take three bytes each but one cell.
+		width := lipgloss.Width(line)
+		pad := max(0, prevWidth-width)
+		prevWidth = width
+		_, err := io.WriteString(w, "\r"+line+strings.Repeat(" ", pad))
+		return err
+	})
+	fmt.Fprintln(w)
+	return final, err
+}
+
+// renderOneline formats every shown port as "mlx5_0:1 ↓12.3 ↑8.1", optionally
+// with percentages of line rate, joined by sep.
+func (m model) renderOneline(sep string, showPct bool) string {
+	var parts []string
+	for _, stat := range m.statuses {
+		if m.hideIdle && stat.idle() {
+			continue
+		}
+		rx, tx := stat.displayValues()
+		rxStr := fmt.Sprintf("↓%.*f", m.precision, m.displayValue(rx))
+		txStr := fmt.Sprintf("↑%.*f", m.precision, m.displayValue(tx))
+		if showPct {
+			rxStr += fmt.Sprintf(" %d%%", int(lineFraction(rx, stat.iface.MaxGbps)*100))
+			txStr += fmt.Sprintf(" %d%%", int(lineFraction(tx, stat.iface.MaxGbps)*100))
+		}
+		part := stat.name() + " " + rxStr + " " + txStr
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,102 @@
+//go:build otel
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/apsu/ibmon/ibmon"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// otelExporter records per-port metrics with the OpenTelemetry SDK and
+// pushes them over OTLP/gRPC every interval. Adaptor and port are attributes
+// on each data point; the resource identifies the ibmon process and host.
+type otelExporter struct {
+	provider *sdkmetric.MeterProvider
+	rxGbps   metric.Float64Gauge
+	txGbps   metric.Float64Gauge
+	rxBytes  metric.Int64Counter
+	txBytes  metric.Int64Counter
+	wraps    metric.Int64Counter
+}
+
+// newOtelExporter connects to an OTLP/gRPC collector at endpoint
+// ("host:port", plaintext) and registers the ibmon instruments.
+func newOtelExporter(endpoint string, interval time.Duration) (metricsExporter, error) {
+	ctx := context.Background()
+	exp, err := otlpmetricgrpc.New(ctx, otlpmetricgrpc.WithEndpoint(endpoint), otlpmetricgrpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName("ibmon")))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exp, sdkmetric.WithInterval(interval))),
+	)
+	meter := provider.Meter("github.com/apsu/ibmon")
+
+	o := &otelExporter{provider: provider}
+	if o.rxGbps, err = meter.Float64Gauge("ibmon.rx", metric.WithUnit("Gbit/s"), metric.WithDescription("Receive throughput")); err != nil {
+		return nil, err
+	}
+	if o.txGbps, err = meter.Float64Gauge("ibmon.tx", metric.WithUnit("Gbit/s"), metric.WithDescription("Transmit throughput")); err != nil {
+		return nil, err
+	}
+	if o.rxBytes, err = meter.Int64Counter("ibmon.rx.bytes", metric.WithUnit("By"), metric.WithDescription("Bytes received")); err != nil {
+		return nil, err
+	}
+	if o.txBytes, err = meter.Int64Counter("ibmon.tx.bytes", metric.WithUnit("By"), metric.WithDescription("Bytes transmitted")); err != nil {
+		return nil, err
+	}
+	if o.wraps, err = meter.Int64Counter("ibmon.counter.wraps", metric.WithDescription("Data counter wraps; steady growth means 32-bit counters")); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// emit adds the sample of every port sampled in snap to the instruments.
+func (o *otelExporter) emit(snap snapshot) error {
+	for _, p := range snap.Interfaces {
+		if p.sampled {
+			o.record(p, p.sample)
+		}
+	}
+	return nil
+}
+
+// record adds one sample of a port to the instruments.
+func (o *otelExporter) record(p ifaceSnapshot, t ibmon.Throughput) {
+	ctx := context.Background()
+	attrs := metric.WithAttributes(
+		attribute.String("adaptor", p.Adaptor),
+		attribute.String("port", p.Port),
+	)
+	o.rxGbps.Record(ctx, t.RxGbps, attrs)
+	o.txGbps.Record(ctx, t.TxGbps, attrs)
+	if t.RxBytes > 0 {
+		o.rxBytes.Add(ctx, t.RxBytes, attrs)
+	}
+	if t.TxBytes > 0 {
+		o.txBytes.Add(ctx, t.TxBytes, attrs)
+	}
+	if t.Wraps > 0 {
+		o.wraps.Add(ctx, int64(t.Wraps), attrs)
+	}
+}
+
+// Shutdown flushes pending metrics and stops the exporter.
+func (o *otelExporter) Shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return o.provider.Shutdown(ctx)
+}
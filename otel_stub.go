@@ -0,0 +1,14 @@
+//go:build !otel
+
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// newOtelExporter reports that OpenTelemetry export was left out of this
+// build; build with -tags otel to include it.
+func newOtelExporter(endpoint string, interval time.Duration) (metricsExporter, error) {
+	return nil, errors.New("-otlp: ibmon was built without OpenTelemetry support (rebuild with -tags otel)")
+}
@@ -0,0 +1,23 @@
+package main
+
+// togglePause stops or resumes polling the selected port, for the 'p' key,
+// as a lighter alternative to restarting with -ignore. A paused port reads
+// nothing, shows no rates and adds nothing to group rows or totals.
+func (m *model) togglePause() {
+	if m.grouped || m.selected < 0 || m.selected >= len(m.statuses) {
+		m.setNotice("select a port with ↑/↓ to pause or resume it")
+		return
+	}
+	stat := &m.statuses[m.selected]
+	stat.paused = !stat.paused
+	if stat.paused {
+		stat.rxValue, stat.txValue = 0, 0
+		stat.rxHistory, stat.txHistory = nil, nil
+		m.setNotice(stat.name() + ": polling paused")
+		return
+	}
+	// The first sample after resuming would otherwise cover the whole pause
+	// and spike.
+	stat.rebaseCounters()
+	m.setNotice(stat.name() + ": polling resumed")
+}
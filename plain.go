@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ANSI sequences used by the plain watch mode.
+const (
+	ansiClear      = "\x1b[H\x1b[2J"
+	ansiHideCursor = "\x1b[?25l"
+	ansiShowCursor = "\x1b[?25h"
+)
+
+// runPlain redraws an aligned table on the main screen every interval, in the
+// manner of watch(1). It shares sampling and formatting with the TUI but has
+// no viewport or key handling; Ctrl-C restores the cursor and exits.
+func runPlain(m model, w io.Writer) (model, error) {
+	fmt.Fprint(w, ansiHideCursor)
+	defer fmt.Fprint(w, ansiShowCursor)
+
+	return runHeadless(m, func(m model) error {
+		_, err := fmt.Fprint(w, ansiClear+m.renderTable())
+		return err
+	})
+}
+
+// renderTable formats the current readings as a plain aligned table.
+func (m model) renderTable() string {
+	var b strings.Builder
+	nameWidth := len("INTERFACE")
+	for _, stat := range m.statuses {
+		nameWidth = max(nameWidth, len(stat.name()))
+	}
+
+	rateWidth := max(m.rateWidth(), 9) // never narrower than the default layout
+	fmt.Fprintf(&b, "%-*s %6s  %-*s %5s  %-*s %5s\n", nameWidth, "INTERFACE", "RATE", rateWidth, "RX", "RX%", rateWidth, "TX", "TX%")
+	for _, stat := range m.statuses {
+		rx, tx := stat.displayValues()
+		fmt.Fprintf(&b, "%-*s %6s  %-*s %4d%%  %-*s %4d%%\n",
+			nameWidth, stat.name(),
+			fmt.Sprintf("%dG", int(stat.iface.MaxGbps)),
+			rateWidth, m.formatRate(rx), int(lineFraction(rx, stat.iface.MaxGbps)*100),
+			rateWidth, m.formatRate(tx), int(lineFraction(tx, stat.iface.MaxGbps)*100))
+	}
+	return b.String()
+}
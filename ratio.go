@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// asymmetryRatio is the ratio of the busier direction to the other from
+// which a port is flagged as strongly one-sided, which often means a stuck
+// receiver or a misrouted flow.
+const asymmetryRatio = 10
+
+// ratioWidth is the width of the 'a' column: a space and the widest label,
+// "999.9:1 ↓ ⚠".
+const ratioWidth = 12
+
+// ratioLabel describes the balance between rx and tx for the 'a' column:
+// the ratio of the busier direction to the other and an arrow for the
+// busier one, e.g. "3.2:1 ↓". A direction at idle makes the port one-sided
+// rather than dividing by zero, and both at idle read "-". asymmetric is set
+// from asymmetryRatio on.
+func ratioLabel(rx, tx float64) (label string, asymmetric bool) {
+	hi, lo, arrow := rx, tx, "↓"
+	if tx > rx {
+		hi, lo, arrow = tx, rx, "↑"
+	}
+	switch {
+	case hi < idleThresholdGbps:
+		return "-", false
+	case lo < idleThresholdGbps:
+		return "only " + arrow, true
+	}
+	r := hi / lo
+	if r >= 999.95 {
+		return ">999:1 " + arrow, true
+	}
+	return fmt.Sprintf("%.1f:1 %s", r, arrow), r >= asymmetryRatio
+}
+
+// renderRatio renders a port's 'a' column from its displayed rates, right
+// aligned, and in red with a warning sign when the port is strongly
+// asymmetric.
+func renderRatio(stat ifaceStatus) string {
+	label, asymmetric := ratioLabel(stat.displayValues())
+	if asymmetric {
+		label += " ⚠"
+	}
+	col := strings.Repeat(" ", max(1, ratioWidth-lipgloss.Width(label))) + label
+	if asymmetric {
+		return warnStyle.Render(col)
+	}
+	return col
+}
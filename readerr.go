@@ -0,0 +1,47 @@
+package main
+
+import "time"
+
+// A port whose counters fail to read readErrorLimit ticks running is marked
+// as failing: its row shows the error in place of its bars, snapshots carry
+// it, and it is only retried every readErrorRetry rather than every tick,
+// so a wedged device or a permissions change costs neither a syscall storm
+// nor silence. The first successful read clears it.
+const (
+	readErrorLimit = 3
+	readErrorRetry = 10 * time.Second
+)
+
+// trackRead records the outcome of reading the port's counters at now.
+func (s *ifaceStatus) trackRead(err error, now time.Time) {
+	if err == nil {
+		s.readErr, s.readFailures = nil, 0
+		return
+	}
+	s.readErr = err
+	s.readFailures++
+	if s.readFailing() {
+		s.readRetryAt = now.Add(readErrorRetry)
+	}
+}
+
+// readFailing reports whether the port has failed enough reads in a row to
+// be shown as failing and retried only occasionally.
+func (s ifaceStatus) readFailing() bool {
+	return s.readFailures >= readErrorLimit
+}
+
+// readDue reports whether the port's counters are to be read at now: always,
+// unless it is failing and not yet due a retry.
+func (s ifaceStatus) readDue(now time.Time) bool {
+	return !s.readFailing() || !now.Before(s.readRetryAt)
+}
+
+// readError returns the error of a failing port for display and snapshots,
+// or "" if it is not failing.
+func (s ifaceStatus) readError() string {
+	if !s.readFailing() {
+		return ""
+	}
+	return s.readErr.Error()
+}
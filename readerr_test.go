@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTrackRead(t *testing.T) {
+	now := time.Unix(1000, 0)
+	var s ifaceStatus
+	errRead := errors.New("device wedged")
+	for i := range readErrorLimit {
+		if s.readFailing() || !s.readDue(now) {
+			t.Fatalf("failing after %d errors, want only after %d", i, readErrorLimit)
+		}
+		s.trackRead(errRead, now)
+	}
+	if !s.readFailing() || s.readError() != errRead.Error() {
+		t.Fatalf("readFailing, readError = %v, %q after %d errors", s.readFailing(), s.readError(), readErrorLimit)
+	}
+	if s.readDue(now.Add(readErrorRetry - time.Millisecond)) {
+		t.Error("a failing port is retried before readErrorRetry")
+	}
+	if !s.readDue(now.Add(readErrorRetry)) {
+		t.Error("a failing port is not retried after readErrorRetry")
+	}
+
+	s.trackRead(nil, now.Add(readErrorRetry))
+	if s.readFailing() || s.readError() != "" || !s.readDue(now) {
+		t.Error("a successful read does not clear the failure")
+	}
+}
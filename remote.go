@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apsu/ibmon/ibmon"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// remoteStaleAfter is how many intervals a host may go without a successful
+// read before its rows are marked stale.
+const remoteStaleAfter = 3
+
+// remotePort identifies one port discovered on a remote host.
+type remotePort struct {
+	adaptor string
+	port    string
+	rate    string
+}
+
+// remoteReading is the latest counter values read for one remote port.
+type remoteReading struct {
+	rx, tx int64
+	ok     bool // false if the counter files could not be read
+}
+
+// remoteHost polls the InfiniBand counters of one host over SSH. Discovery
+// and each round of counter reads run as a single remote shell command, and
+// polling happens in the background so a slow or unreachable host never
+// blocks the UI.
+type remoteHost struct {
+	name     string // host as given on the command line, used as the row label
+	addr     string // host:port to dial
+	config   *ssh.ClientConfig
+	sysfs    string
+	interval time.Duration
+	ports    []remotePort
+
+	mu       sync.Mutex
+	client   *ssh.Client
+	readings []remoteReading
+	readAt   time.Time // when readings were taken
+	lastErr  error     // latest read failure, shown on stale rows
+}
+
+// newRemoteHost connects to target ("[user@]host[:port]"), discovers its
+// ports under the sysfs root and takes an initial counter reading. Auth uses
+// the running ssh-agent and host keys are checked against ~/.ssh/known_hosts.
+func newRemoteHost(target, sysfs string, interval time.Duration) (*remoteHost, error) {
+	userName, host := "", target
+	if at := strings.LastIndex(target, "@"); at >= 0 {
+		userName, host = target[:at], target[at+1:]
+	}
+	if userName == "" {
+		u, err := user.Current()
+		if err != nil {
+			return nil, err
+		}
+		userName = u.Username
+	}
+	// Without a port, an IPv6 address may be bare or in brackets.
+	addr := host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		addr = net.JoinHostPort(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"), "22")
+	}
+
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, fmt.Errorf("%s: SSH_AUTH_SOCK is not set; an ssh-agent is required", target)
+	}
+	agentConn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, fmt.Errorf("%s: connecting to ssh-agent: %w", target, err)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	hostKeys, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", target, err)
+	}
+
+	h := &remoteHost{
+		name: target,
+		addr: addr,
+		config: &ssh.ClientConfig{
+			User:            userName,
+			Auth:            []ssh.AuthMethod{ssh.PublicKeysCallback(agent.NewClient(agentConn).Signers)},
+			HostKeyCallback: hostKeys,
+			Timeout:         5 * time.Second,
+		},
+		sysfs:    sysfs,
+		interval: interval,
+	}
+	if err := h.discover(); err != nil {
+		return nil, fmt.Errorf("%s: %w", target, err)
+	}
+	if err := h.poll(); err != nil {
+		return nil, fmt.Errorf("%s: %w", target, err)
+	}
+	go h.run()
+	return h, nil
+}
+
+// run polls the host's counters every interval, reconnecting as needed.
+func (h *remoteHost) run() {
+	for range time.Tick(h.interval) {
+		h.poll()
+	}
+}
+
+// exec executes a shell command on the host, dialing first if not connected.
+// A failed command drops the connection so the next call redials.
+func (h *remoteHost) exec(cmd string) ([]byte, error) {
+	h.mu.Lock()
+	client := h.client
+	h.mu.Unlock()
+	if client == nil {
+		c, err := ssh.Dial("tcp", h.addr, h.config)
+		if err != nil {
+			return nil, err
+		}
+		h.mu.Lock()
+		h.client = c
+		h.mu.Unlock()
+		client = c
+	}
+
+	session, err := client.NewSession()
+	if err == nil {
+		defer session.Close()
+		var out []byte
+		if out, err = session.Output(cmd); err == nil {
+			return out, nil
+		}
+	}
+	client.Close()
+	h.mu.Lock()
+	h.client = nil
+	h.mu.Unlock()
+	return nil, err
+}
+
+// discover lists the host's ports and their rates, one "adaptor port rate"
+// line per port.
+func (h *remoteHost) discover() error {
+	script := fmt.Sprintf(`for p in %s/*/ports/*; do [ -r "$p/counters/port_rcv_data" ] || continue; `+
+		`a=${p%%/ports/*}; echo "${a##*/} ${p##*/} $(cat "$p/rate" 2>/dev/null)"; done`, shellQuote(h.sysfs))
+	out, err := h.exec(script)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), " ", 3)
+		if len(fields) < 2 {
+			continue
+		}
+		p := remotePort{adaptor: fields[0], port: fields[1]}
+		if len(fields) == 3 {
+			p.rate = fields[2]
+		}
+		h.ports = append(h.ports, p)
+	}
+	if len(h.ports) == 0 {
+		return fmt.Errorf("no interfaces found")
+	}
+	// Order as local discovery does, whatever the remote shell's locale.
+	slices.SortFunc(h.ports, func(a, b remotePort) int {
+		return cmp.Or(strings.Compare(a.adaptor, b.adaptor), strings.Compare(a.port, b.port))
+	})
+	return nil
+}
+
+// poll reads every port's RX and TX counters in one command and stores them
+// with the time of the read. Unreadable files print "-" to keep the output
+// aligned with the port list.
+func (h *remoteHost) poll() error {
+	var files []string
+	for _, p := range h.ports {
+		dir := path.Join(h.sysfs, p.adaptor, "ports", p.port, "counters")
+		files = append(files, shellQuote(path.Join(dir, "port_rcv_data")), shellQuote(path.Join(dir, "port_xmit_data")))
+	}
+	out, err := h.exec("for f in " + strings.Join(files, " ") + `; do cat "$f" 2>/dev/null || echo -; done`)
+	readAt := time.Now()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastErr = err
+	if err != nil {
+		return err
+	}
+	values := strings.Fields(string(out))
+	if len(values) != 2*len(h.ports) {
+		h.lastErr = fmt.Errorf("unexpected counter output")
+		return h.lastErr
+	}
+	readings := make([]remoteReading, len(h.ports))
+	for i := range h.ports {
+		rx, rxErr := strconv.ParseInt(values[2*i], 10, 64)
+		tx, txErr := strconv.ParseInt(values[2*i+1], 10, 64)
+		readings[i] = remoteReading{rx: rx, tx: tx, ok: rxErr == nil && txErr == nil}
+	}
+	h.readings = readings
+	h.readAt = readAt
+	return nil
+}
+
+// interfaces builds an Interface for each discovered port, primed with the
+// latest counter reading.
+func (h *remoteHost) interfaces() []ibmon.Interface {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ifaces := make([]ibmon.Interface, len(h.ports))
+	for i, p := range h.ports {
+		r := h.readings[i]
+		ifaces[i] = ibmon.NewInterface(p.adaptor, p.port, p.rate, r.rx, r.tx)
+	}
+	return ifaces
+}
+
+// reading returns the latest counters for port i, when they were read and
+// the latest read failure, if any. stale is set only once the host has not
+// been read successfully for remoteStaleAfter intervals: a single failed
+// round keeps the rows on their last reading.
+func (h *remoteHost) reading(i int) (r remoteReading, at time.Time, stale bool, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stale = time.Since(h.readAt) > remoteStaleAfter*h.interval
+	return h.readings[i], h.readAt, stale, h.lastErr
+}
+
+// shellQuote quotes s for safe use as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/apsu/ibmon/ibmon"
+)
+
+// replayPort is one port found in a -replay file, named by host (empty for
+// local ports) and adaptor:port.
+type replayPort struct {
+	host, adaptor, port string
+	maxGbps             float64
+}
+
+// replayRow is one port's readings within a recorded snapshot.
+type replayRow struct {
+	rxGbps, txGbps   float64
+	rxBytes, txBytes uint64 // running totals, as logged
+	ok               bool   // false if the port is missing from the snapshot
+	stale            bool   // recorded stale: the host had stopped answering
+}
+
+// replaySource plays back a CSV written by -logfile with -logformat csv,
+// one recorded snapshot per tick, in place of reading counters.
+type replaySource struct {
+	ports  []replayPort
+	times  []time.Time
+	frames [][]replayRow // frames[n][i] is port i in snapshot n
+	loop   bool          // start over at the end instead of stopping
+	demo   bool          // generated by -demo rather than loaded from a log
+
+	pos  int  // current frame; -1 before the first tick
+	done bool // the last frame is current and loop is off
+}
+
+// loadReplay reads a CSV log into memory. Consecutive rows with the same
+// time make up one snapshot; the ports are every distinct host and
+// adaptor:port, in order of first appearance. Logs from before the host
+// column are read as all local.
+func loadReplay(path string, loop bool) (*replaySource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Every row must have as many fields as the header.
+	r := csv.NewReader(f)
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("replay %s: %w", path, err)
+	}
+	hasHost := slices.Equal(header, csvHeader)
+	if !hasHost && !slices.Equal(header, csvHeaderNoHost) {
+		return nil, fmt.Errorf("replay %s: not an ibmon CSV log (want header %v)", path, csvHeader)
+	}
+
+	src := &replaySource{loop: loop, pos: -1}
+	index := make(map[string]int) // host and adaptor:port -> index into ports
+	type record struct {
+		port int
+		row  replayRow
+	}
+	var frame []record
+	var frameTime time.Time
+	flush := func() {
+		if frame == nil {
+			return
+		}
+		rows := make([]replayRow, len(src.ports))
+		for _, rec := range frame {
+			rows[rec.port] = rec.row
+		}
+		src.times = append(src.times, frameTime)
+		src.frames = append(src.frames, rows)
+		frame = nil
+	}
+	for {
+		fields, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("replay %s: %w", path, err)
+		}
+		line, _ := r.FieldPos(0)
+		t, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("replay %s:%d: %w", path, line, err)
+		}
+		var host string
+		var stale bool
+		if hasHost {
+			host = fields[1]
+			if stale, err = strconv.ParseBool(fields[9]); err != nil {
+				return nil, fmt.Errorf("replay %s:%d: stale: %w", path, line, err)
+			}
+			fields = fields[1:] // so the columns after it line up with csvHeaderNoHost
+		}
+		var nums [3]float64
+		for i, s := range fields[3:6] {
+			if nums[i], err = strconv.ParseFloat(s, 64); err != nil {
+				return nil, fmt.Errorf("replay %s:%d: %s: %w", path, line, csvHeaderNoHost[3+i], err)
+			}
+		}
+		var totals [2]uint64
+		for i, s := range fields[6:8] {
+			if totals[i], err = strconv.ParseUint(s, 10, 64); err != nil {
+				return nil, fmt.Errorf("replay %s:%d: %s: %w", path, line, csvHeaderNoHost[6+i], err)
+			}
+		}
+
+		if !t.Equal(frameTime) {
+			flush()
+			frameTime = t
+		}
+		name := host + " " + fields[1] + ":" + fields[2]
+		i, ok := index[name]
+		if !ok {
+			i = len(src.ports)
+			index[name] = i
+			src.ports = append(src.ports, replayPort{host: host, adaptor: fields[1], port: fields[2], maxGbps: nums[0]})
+		}
+		frame = append(frame, record{port: i, row: replayRow{
+			rxGbps: nums[1], txGbps: nums[2],
+			rxBytes: totals[0], txBytes: totals[1],
+			ok: true, stale: stale,
+		}})
+	}
+	flush()
+	if len(src.frames) == 0 {
+		return nil, fmt.Errorf("replay %s: no snapshots", path)
+	}
+	// Frames read before a port first appeared are short; pad them.
+	for n, rows := range src.frames {
+		if len(rows) < len(src.ports) {
+			src.frames[n] = append(rows, make([]replayRow, len(src.ports)-len(rows))...)
+		}
+	}
+	return src, nil
+}
+
+// interval returns the recorded time between the first two snapshots, or 0
+// if there are fewer than two.
+func (r *replaySource) interval() time.Duration {
+	if len(r.times) < 2 {
+		return 0
+	}
+	return r.times[1].Sub(r.times[0])
+}
+
+// replayInterval returns the tick for playing back a recording taken every
+// recorded at speed times real time. Like -interval, it must lie within
+// minInterval..maxInterval.
+func replayInterval(recorded time.Duration, speed float64) (time.Duration, error) {
+	d := time.Duration(float64(recorded) / speed)
+	if err := validateInterval(d); err != nil {
+		return 0, fmt.Errorf("invalid -replay-speed %v: the %v recording would be sampled every %v, outside %v..%v", speed, recorded, d, minInterval, maxInterval)
+	}
+	return d, nil
+}
+
+// interfaces builds an Interface for each recorded port. Only the line rate
+// is known; there is no rate string or counter to read.
+func (r *replaySource) interfaces() []ibmon.Interface {
+	ifaces := make([]ibmon.Interface, len(r.ports))
+	for i, p := range r.ports {
+		ifaces[i] = ibmon.NewInterface(p.adaptor, p.port, "", 0, 0)
+		ifaces[i].MaxGbps = p.maxGbps
+	}
+	return ifaces
+}
+
+// advance moves to the next snapshot, wrapping around at the end of the
+// recording with loop, or else marking the replay done on its last snapshot.
+func (r *replaySource) advance() {
+	r.pos++
+	if r.pos == len(r.frames) {
+		r.pos = 0
+	}
+	r.done = !r.loop && r.pos == len(r.frames)-1
+}
+
+// stale reports whether port i was recorded stale in the current snapshot.
+func (r *replaySource) stale(i int) bool {
+	return r.pos >= 0 && r.frames[r.pos][i].stale
+}
+
+// reading returns port i's throughput in the current snapshot, with the
+// bytes moved since the previous one. ok is false if the port is missing.
+func (r *replaySource) reading(i int) (t ibmon.Throughput, ok bool) {
+	if r.pos < 0 {
+		return ibmon.Throughput{}, false
+	}
+	row := r.frames[r.pos][i]
+	if !row.ok {
+		return ibmon.Throughput{}, false
+	}
+	t = ibmon.Throughput{RxGbps: row.rxGbps, TxGbps: row.txGbps}
+	// Byte deltas come from the running totals; the first frame, and the
+	// first after looping, have nothing to compare with.
+	if r.pos > 0 {
+		if prev := r.frames[r.pos-1][i]; prev.ok {
+			t.RxBytes = int64(row.rxBytes) - int64(prev.rxBytes)
+			t.TxBytes = int64(row.txBytes) - int64(prev.txBytes)
+		}
+	}
+	return t, true
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReplayKeepsHosts(t *testing.T) {
+	// Two -remote hosts with the same adaptor:port stay two ports when
+	// their CSV log is played back.
+	path := filepath.Join(t.TempDir(), "ibmon.csv")
+	l, err := newLogSink(path, logFormatCSV, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 2 {
+		snap := snapshot{Time: t0.Add(time.Duration(i) * time.Second), Interfaces: []ifaceSnapshot{
+			{Host: "node1", Adaptor: "mlx5_0", Port: "1", MaxGbps: 400, RxGbps: 10},
+			{Host: "node2", Adaptor: "mlx5_0", Port: "1", MaxGbps: 400, RxGbps: 20, Stale: i == 1},
+		}}
+		if err := l.emit(snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Close()
+
+	r, err := loadReplay(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.ports) != 2 || r.ports[0].host != "node1" || r.ports[1].host != "node2" {
+		t.Fatalf("replayed ports %+v, want mlx5_0:1 on node1 and on node2", r.ports)
+	}
+	r.advance()
+	r.advance()
+	if got, _ := r.reading(1); got.RxGbps != 20 || !r.stale(1) || r.stale(0) {
+		t.Errorf("node2 in the last frame: %v Gbps, stale %v; want 20, stale", got.RxGbps, r.stale(1))
+	}
+
+	// The old header, without host and stale, still plays back as local.
+	old := filepath.Join(t.TempDir(), "old.csv")
+	content := "time,adaptor,port,max_gbps,rx_gbps,tx_gbps,rx_bytes,tx_bytes\n" +
+		"2024-01-01T00:00:00Z,mlx5_0,1,400,1,2,0,0\n"
+	if err := os.WriteFile(old, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := loadReplay(old, false); err != nil || len(r.ports) != 1 || r.ports[0].host != "" {
+		t.Errorf("loading an old-format log: %v, %+v", err, r)
+	}
+
+	// Appending to it would mix the two layouts.
+	if _, err := newLogSink(old, logFormatCSV, 0, 0); err == nil {
+		t.Error("newLogSink appended to a CSV log with another header")
+	}
+}
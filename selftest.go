@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/apsu/ibmon/ibmon"
+)
+
+// permissionHint is the remediation printed for unreadable sysfs files.
+const permissionHint = "run as root, or grant the binary CAP_DAC_READ_SEARCH (setcap cap_dac_read_search+ep ibmon)"
+
+// runSelftest checks, for -selftest, that everything ibmon reads is there
+// and readable: the sysfs root, then every adaptor and port, with each
+// port's counters and rate file. It writes a line per check and a PASS or
+// FAIL verdict to w, with hints for the problems found, and reports whether
+// the checks passed: at least one port is usable and nothing was refused
+// for lack of permission. Unlike -list, which shows what was found, it
+// explains why something could not be used.
+func runSelftest(w io.Writer, opts ibmon.Options) bool {
+	root := opts.SysfsPath
+	if root == "" {
+		root = ibmon.DefaultSysfsPath
+	}
+	// Permission errors fail the test; anything else missing is only a
+	// warning, as long as some port is usable.
+	denied := false
+	fail := func(what string, err error) {
+		if errors.Is(err, fs.ErrPermission) {
+			fmt.Fprintf(w, "FAIL  %s: %v\n", what, err)
+			denied = true
+			return
+		}
+		fmt.Fprintf(w, "warn  %s: %v\n", what, err)
+	}
+
+	if _, err := os.ReadDir(root); err != nil {
+		fmt.Fprintf(w, "FAIL  %s: %v\n", root, err)
+		denied = errors.Is(err, fs.ErrPermission)
+		if errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintln(w, "hint: no InfiniBand/RDMA driver is loaded, or -sysfs points elsewhere")
+		}
+		return verdict(w, 0, denied)
+	}
+	fmt.Fprintf(w, "ok    %s readable\n", root)
+
+	opts.OnSkip = func(name string, reason error) {
+		if opts.Ignore[name] {
+			fmt.Fprintf(w, "skip  %s: %v\n", name, reason)
+			return
+		}
+		fail(name, reason)
+	}
+	ifaces, err := ibmon.Discover(opts)
+	if err != nil {
+		fail(root, err)
+		return verdict(w, 0, denied)
+	}
+	usable := 0
+	for i := range ifaces {
+		iface := &ifaces[i]
+		name := iface.Adaptor + ":" + iface.Port
+		if _, _, err := iface.ReadCounters(); err != nil {
+			fail(name+" counters", err)
+			continue
+		}
+		rate := "rate " + iface.Rate
+		if _, err := os.ReadFile(filepath.Join(root, iface.Adaptor, "ports", iface.Port, "rate")); err != nil {
+			// Not fatal: the port is shown, only without a line rate.
+			rate = fmt.Sprintf("rate unreadable (%v), shown without a line rate", err)
+			denied = denied || errors.Is(err, fs.ErrPermission)
+		}
+		fmt.Fprintf(w, "ok    %s counters readable (%s profile, %s/), %s\n", name, iface.Profile, iface.CounterSource, rate)
+		usable++
+	}
+	return verdict(w, usable, denied)
+}
+
+// verdict writes the selftest summary with its hints and returns whether it
+// passed.
+func verdict(w io.Writer, ports int, denied bool) bool {
+	if denied {
+		fmt.Fprintln(w, "hint: "+permissionHint)
+	}
+	if ports == 0 {
+		fmt.Fprintln(w, "FAIL: no usable ports")
+		return false
+	}
+	if denied {
+		fmt.Fprintf(w, "FAIL: %d usable ports, but some files could not be read\n", ports)
+		return false
+	}
+	fmt.Fprintf(w, "PASS: %d usable ports\n", ports)
+	return true
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/apsu/ibmon/ibmon"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// signalMsg delivers a process signal to the model so it can be handled on
+// the Update goroutine.
+type signalMsg struct {
+	sig os.Signal
+}
+
+// forwardSignals relays SIGINT, SIGTERM and SIGHUP to the program. It replaces
+// Bubble Tea's own handler so that termination goes through Update and
+// tea.Quit, restoring the terminal and letting deferred cleanup in main run.
+func forwardSignals(p *tea.Program) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := range ch {
+		p.Send(signalMsg{sig: sig})
+	}
+}
+
+// handleSignal quits on SIGINT/SIGTERM and re-runs discovery on SIGHUP.
+func (m model) handleSignal(sig os.Signal) (model, tea.Cmd) {
+	if sig != syscall.SIGHUP {
+		return m, tea.Quit
+	}
+	if err := m.rediscover(); err != nil {
+		m.setNotice(fmt.Sprintf("rediscovery failed: %v", err))
+	} else {
+		m.setNotice(fmt.Sprintf("rediscovered %d interfaces", len(m.statuses)))
+	}
+	m.refresh()
+	return m, nil
+}
+
+// rediscover re-runs interface discovery and rebuilds the status list in
+// place. Ports seen before keep their counters and accumulated state; new
+// ports start fresh and vanished ones are dropped. On error the current list
+// is kept. Remote hosts are discovered once at startup and are left as is,
+// as is a -replay.
+func (m *model) rediscover() error {
+	if m.replay != nil || len(m.statuses) > 0 && m.statuses[0].host != nil {
+		return nil
+	}
+	ifaces, err := ibmon.Discover(m.discover)
+	if err != nil {
+		return err
+	}
+	if len(ifaces) == 0 {
+		return fmt.Errorf("no interfaces found")
+	}
+
+	existing := make(map[string]ifaceStatus, len(m.statuses))
+	for _, stat := range m.statuses {
+		existing[stat.name()] = stat
+	}
+	statuses := make([]ifaceStatus, 0, len(ifaces))
+	for _, iface := range ifaces {
+		stat, ok := existing[iface.Adaptor+":"+iface.Port]
+		if !ok {
+			stat = ifaceStatus{iface: iface}
+		}
+		statuses = append(statuses, stat)
+	}
+	m.statuses = statuses
+	return nil
+}
+
+// noticeDuration is how long a footer notice stays visible.
+const noticeDuration = 3 * time.Second
+
+// setNotice shows a short message in place of the footer key hints.
+func (m *model) setNotice(msg string) {
+	m.notice = msg
+	m.noticeUntil = time.Now().Add(noticeDuration)
+}
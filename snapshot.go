@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/apsu/ibmon/ibmon"
+)
+
+// snapshot is one tick's worth of readings for all interfaces. It is the
+// document written per line by -json and streamed to -socket clients. With
+// -remote it merges every host's ports, ordered by host as given and then by
+// adaptor:port.
+type snapshot struct {
+	Time       time.Time       `json:"time"`
+	Version    string          `json:"version"`    // ibmon version that took the snapshot
+	Commit     string          `json:"commit"`     // its git commit, see buildInfo
+	BuildDate  string          `json:"build_date"` // when it was built, see buildInfo
+	Interfaces []ifaceSnapshot `json:"interfaces"`
+}
+
+// ifaceSnapshot holds the readings for a single port within a snapshot.
+// Host and Stale are only set for -remote ports, and for ports replayed
+// from a log that recorded them; a stale port keeps its last rates and
+// totals. ReadError is set while a local port's counters keep
+// failing to read, see trackRead; it too keeps its last rates and totals.
+type ifaceSnapshot struct {
+	Host    string  `json:"host,omitempty"`
+	Stale   bool    `json:"stale,omitempty"` // no recent counter reading from host
+	Adaptor string  `json:"adaptor"`
+	Port    string  `json:"port"`
+	MaxGbps float64 `json:"max_gbps"`
+	RxGbps  float64 `json:"rx_gbps"`
+	TxGbps  float64 `json:"tx_gbps"`
+	RxBytes uint64  `json:"rx_bytes"`            // bytes received since start or reset
+	TxBytes uint64  `json:"tx_bytes"`            // bytes transmitted since start or reset
+	Wraps   uint64  `json:"counter_wraps_total"` // counter wraps since start or reset
+
+	ReadError string `json:"read_error,omitempty"`
+
+	// The port's sample on this tick, for the in-process emitters only:
+	// sampled is unset when the port was not sampled, e.g. while paused.
+	sampled bool
+	sample  ibmon.Throughput
+	errors  []ibmon.Counter // error counters read with the sample, if alerting
+}
+
+// name returns the port's row label, as ifaceStatus.name does.
+func (p ifaceSnapshot) name() string {
+	name := p.Adaptor + ":" + p.Port
+	if p.Host != "" {
+		name = p.Host + " " + name
+	}
+	return name
+}
+
+// snapshot captures the current raw (unsmoothed) readings of every interface.
+func (m model) snapshot(t time.Time) snapshot {
+	ver, rev, date := buildInfo()
+	snap := snapshot{
+		Time:       t,
+		Version:    ver,
+		Commit:     rev,
+		BuildDate:  date,
+		Interfaces: make([]ifaceSnapshot, 0, len(m.statuses)),
+	}
+	for _, stat := range m.statuses {
+		snap.Interfaces = append(snap.Interfaces, ifaceSnapshot{
+			Host:    stat.hostName(),
+			Stale:   stat.stale,
+			Adaptor: stat.iface.Adaptor,
+			Port:    stat.iface.Port,
+			MaxGbps: stat.iface.MaxGbps,
+			RxGbps:  stat.rxValue,
+			TxGbps:  stat.txValue,
+			RxBytes: stat.rxTotal,
+			TxBytes: stat.txTotal,
+			Wraps:   stat.wraps,
+
+			ReadError: stat.readError(),
+
+			sampled: stat.sampled,
+			sample:  stat.last,
+			errors:  stat.errors,
+		})
+	}
+	return snap
+}
+
+// runHeadless samples on the model's interval without the TUI, publishing
+// each snapshot to the emitters and then calling render, if non-nil, to
+// print the model. An emitter that fails is reported on stderr, once per
+// distinct error, and the run goes on, as it does in the TUI. SIGHUP re-runs
+// discovery. It returns the final model on SIGINT/SIGTERM, once a -count or
+// -duration limit is reached, or when render fails.
+func runHeadless(m model, render func(model) error) (model, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	var lastErr string
+	for {
+		select {
+		case <-ctx.Done():
+			return m, nil
+		case <-hup:
+			m.rediscover()
+		case now := <-ticker.C:
+			m.sample()
+			err := m.publish(m.snapshot(now))
+			if err != nil && err.Error() != lastErr {
+				log.Print(err)
+			}
+			lastErr = ""
+			if err != nil {
+				lastErr = err.Error()
+			}
+			if render != nil {
+				if err := render(m); err != nil {
+					return m, err
+				}
+			}
+			if m.limitReached(now) {
+				return m, nil
+			}
+		}
+	}
+}
+
+// jsonEmitter writes each snapshot as a line of JSON, for -json on stdout.
+type jsonEmitter struct {
+	enc *json.Encoder
+}
+
+// newJSONEmitter returns a jsonEmitter writing to w.
+func newJSONEmitter(w io.Writer) *jsonEmitter {
+	return &jsonEmitter{enc: json.NewEncoder(w)}
+}
+
+func (e *jsonEmitter) emit(snap snapshot) error {
+	return e.enc.Encode(snap)
+}
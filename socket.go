@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"sync"
+)
+
+// socketServer streams newline-delimited JSON snapshots to every client
+// connected to a Unix domain socket, until each client disconnects.
+type socketServer struct {
+	ln      net.Listener
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+}
+
+// newSocketServer listens on path, replacing a stale socket left behind by a
+// previous run, and starts accepting clients in the background.
+func newSocketServer(path string) (*socketServer, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	s := &socketServer{
+		ln:      ln,
+		clients: make(map[chan []byte]struct{}),
+	}
+	go s.serve()
+	return s, nil
+}
+
+// serve accepts clients until the listener is closed.
+func (s *socketServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle forwards published snapshots to one client until it disconnects or
+// a write fails.
+func (s *socketServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	ch := make(chan []byte, 4)
+	s.mu.Lock()
+	s.clients[ch] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, ch)
+		s.mu.Unlock()
+	}()
+
+	// Clients never send anything; a read returning means they hung up.
+	gone := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, conn)
+		close(gone)
+	}()
+
+	for {
+		select {
+		case <-gone:
+			return
+		case line := <-ch:
+			if _, err := conn.Write(line); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// emit queues a snapshot for every connected client. A client that has
+// fallen behind misses the snapshot rather than stalling the sampler.
+func (s *socketServer) emit(snap snapshot) error {
+	line, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.clients {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+	return nil
+}
+
+// Close stops accepting clients and removes the socket file.
+func (s *socketServer) Close() error {
+	return s.ln.Close()
+}
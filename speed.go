@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apsu/ibmon/ibmon"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// downgradeStyle marks ports running below their adaptor's capability.
+var downgradeStyle = lipgloss.NewStyle().Foreground(warnColor).Bold(true)
+
+// speedLabel returns the 's' column for a port: its active rate over its
+// capability, e.g. "400/400" or "100/400 ⚠", and its netdev MTU if known.
+// downgraded is set when the port negotiated below its capability.
+func speedLabel(iface ibmon.Interface) (label string, downgraded bool) {
+	rate := func(gbps float64) string {
+		if gbps == 0 {
+			return "?"
+		}
+		return fmt.Sprint(int(gbps))
+	}
+	label = rate(iface.MaxGbps) + "/" + rate(iface.CapGbps)
+	if iface.MaxGbps < iface.CapGbps {
+		label += " ⚠"
+		downgraded = true
+	}
+	if iface.MTU > 0 {
+		label += fmt.Sprintf(" mtu %d", iface.MTU)
+	}
+	return label, downgraded
+}
+
+// speedWidth returns the width of the 's' column: the longest label plus a
+// leading space.
+func (m model) speedWidth() int {
+	width := 0
+	for _, stat := range m.statuses {
+		label, _ := speedLabel(stat.iface)
+		width = max(width, lipgloss.Width(label)+1)
+	}
+	return width
+}
+
+// renderSpeed renders a port's 's' column, padded to width, in yellow when
+// the port has negotiated down.
+func renderSpeed(iface ibmon.Interface, width int) string {
+	label, downgraded := speedLabel(iface)
+	col := fmt.Sprintf(" %s%*s", label, width-1-lipgloss.Width(label), "")
+	if downgraded {
+		return downgradeStyle.Render(col)
+	}
+	return col
+}
+
+// rateChangeHighlight is how long a row stays highlighted after its link
+// rate changes.
+const rateChangeHighlight = 5 * time.Second
+
+// rateChangedStyle highlights the header of a port whose rate just changed.
+var rateChangedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#000000")).Background(warnColor)
+
+// refreshRates re-reads the rate of every local port once -rate-refresh has
+// passed since the last time, so bars and percentages follow a link that
+// renegotiated since discovery. Changed ports are highlighted for a while
+// and announced in the footer.
+func (m *model) refreshRates(now time.Time) {
+	if m.rateRefresh <= 0 || now.Sub(m.ratesReadAt) < m.rateRefresh {
+		return
+	}
+	m.ratesReadAt = now
+	for i := range m.statuses {
+		s := &m.statuses[i]
+		if s.host != nil || s.replay != nil {
+			continue
+		}
+		old := s.iface.Rate
+		if changed, err := s.iface.RefreshRate(); err != nil || !changed {
+			continue
+		}
+		s.rateChangedAt = now
+		m.setNotice(fmt.Sprintf("%s rate changed: %s → %s", s.name(), dashIfEmpty(old), dashIfEmpty(s.iface.Rate)))
+	}
+}
+
+// rateChanged reports whether stat's rate changed recently enough to still
+// be highlighted.
+func rateChanged(stat ifaceStatus) bool {
+	return !stat.rateChangedAt.IsZero() && time.Since(stat.rateChangedAt) < rateChangeHighlight
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stateSaveInterval is how often -state is rewritten while monitoring; it is
+// also written once more on exit.
+const stateSaveInterval = 30 * time.Second
+
+// stateFile persists each port's run figures across restarts, for -state.
+type stateFile struct {
+	path  string
+	saved time.Time // when the file was last written
+}
+
+// savedState is the JSON document stored in a -state file. Ports are keyed
+// by their row label, e.g. "mlx5_0:1", so the figures map back to the right
+// port whatever order discovery finds them in.
+type savedState struct {
+	Saved time.Time            `json:"saved"`
+	Ports map[string]savedPort `json:"ports"`
+}
+
+// savedPort is one port's persisted figures.
+type savedPort struct {
+	RxPeak      float64          `json:"rx_peak_gbps"`
+	TxPeak      float64          `json:"tx_peak_gbps"`
+	RxTotal     uint64           `json:"rx_bytes"`
+	TxTotal     uint64           `json:"tx_bytes"`
+	HistSamples int              `json:"hist_samples"`
+	RxHist      [histBuckets]int `json:"rx_hist"`
+	TxHist      [histBuckets]int `json:"tx_hist"`
+}
+
+// restore seeds statuses with the figures saved in the file, if it exists.
+// Ports missing from the file start fresh, and saved ports no longer present
+// are ignored.
+func (s *stateFile) restore(statuses []ifaceStatus) error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var st savedState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("state %s: %w", s.path, err)
+	}
+	for i := range statuses {
+		p, ok := st.Ports[statuses[i].name()]
+		if !ok {
+			continue
+		}
+		stat := &statuses[i]
+		stat.rxPeak, stat.txPeak = p.RxPeak, p.TxPeak
+		stat.rxTotal, stat.txTotal = p.RxTotal, p.TxTotal
+		stat.hist = utilHist{rx: p.RxHist, tx: p.TxHist, n: p.HistSamples}
+	}
+	return nil
+}
+
+// save writes the figures of every port to the file. The document goes to a
+// temporary file in the same directory first and is renamed over the old
+// one, so a crash mid-write never leaves a truncated file behind.
+func (s *stateFile) save(statuses []ifaceStatus, now time.Time) error {
+	st := savedState{Saved: now, Ports: make(map[string]savedPort, len(statuses))}
+	for _, stat := range statuses {
+		st.Ports[stat.name()] = savedPort{
+			RxPeak:      stat.rxPeak,
+			TxPeak:      stat.txPeak,
+			RxTotal:     stat.rxTotal,
+			TxTotal:     stat.txTotal,
+			HistSamples: stat.hist.n,
+			RxHist:      stat.hist.rx,
+			TxHist:      stat.hist.tx,
+		}
+	}
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	s.saved = now
+	return nil
+}
+
+// saveDue writes the file if stateSaveInterval has passed since it was last
+// written.
+func (s *stateFile) saveDue(statuses []ifaceStatus, now time.Time) error {
+	if now.Sub(s.saved) < stateSaveInterval {
+		return nil
+	}
+	return s.save(statuses, now)
+}
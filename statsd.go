@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// statsdClient sends DogStatsD-style gauges over a single UDP socket.
+type statsdClient struct {
+	conn net.Conn
+}
+
+// newStatsdClient opens the UDP socket used for all subsequent metrics.
+func newStatsdClient(addr string) (*statsdClient, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &statsdClient{conn: conn}, nil
+}
+
+// gauge sends a single gauge metric tagged with the port's adaptor and port.
+// Send errors are ignored since UDP delivery is best-effort anyway.
+func (c *statsdClient) gauge(name string, value float64, p ifaceSnapshot) {
+	_, _ = c.conn.Write([]byte(formatGauge(name, value, p.Adaptor, p.Port)))
+}
+
+// emit sends the RX and TX gauges of every port sampled in snap.
+func (c *statsdClient) emit(snap snapshot) error {
+	for _, p := range snap.Interfaces {
+		if p.sampled {
+			c.gauge("ibmon.rx_gbps", p.RxGbps, p)
+			c.gauge("ibmon.tx_gbps", p.TxGbps, p)
+		}
+	}
+	return nil
+}
+
+// Close releases the UDP socket.
+func (c *statsdClient) Close() error {
+	return c.conn.Close()
+}
+
+// formatGauge renders a gauge in DogStatsD format, e.g.
+// "ibmon.rx_gbps:12.5|g|#adaptor:mlx5_0,port:1".
+func formatGauge(name string, value float64, adaptor, port string) string {
+	return fmt.Sprintf("%s:%s|g|#adaptor:%s,port:%s",
+		name, strconv.FormatFloat(value, 'f', -1, 64), adaptor, port)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStatsdClientSend(t *testing.T) {
+	ln, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	c, err := newStatsdClient(ln.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.emit(snapshot{Interfaces: []ifaceSnapshot{
+		{Adaptor: "mlx5_0", Port: "1", RxGbps: 12.5, sampled: true},
+		{Adaptor: "mlx5_0", Port: "2", RxGbps: 1}, // paused: not sampled this tick
+	}})
+
+	want := []string{
+		"ibmon.rx_gbps:12.5|g|#adaptor:mlx5_0,port:1",
+		"ibmon.tx_gbps:0|g|#adaptor:mlx5_0,port:1",
+	}
+	buf := make([]byte, 1024)
+	for _, w := range want {
+		ln.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := ln.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("reading packet: %v", err)
+		}
+		if got := string(buf[:n]); got != w {
+			t.Errorf("got %q, want %q", got, w)
+		}
+	}
+}
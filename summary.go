@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// limitReached reports whether a -count or -duration run limit has been hit
+// as of the tick at now, or a -replay without -replay-loop has played its
+// last snapshot.
+func (m model) limitReached(now time.Time) bool {
+	switch {
+	case m.replay != nil && m.replay.done:
+		return true
+	case m.maxTicks > 0:
+		return m.ticks >= m.maxTicks
+	case m.maxDuration > 0:
+		return now.Sub(m.started) >= m.maxDuration
+	}
+	return false
+}
+
+// noteLink takes the port's link state, as read by readPorts, and
+// remembers the first one seen that is not ACTIVE. A state that could not
+// be read counts against the port.
+func (s *ifaceStatus) noteLink(state string, err error) {
+	if s.downState != "" {
+		return
+	}
+	switch {
+	case err != nil:
+		s.downState = "state unreadable"
+	case state != "ACTIVE":
+		s.downState = state
+	}
+}
+
+// trackPeaks folds one raw sample into the run's peak and average figures.
+func (s *ifaceStatus) trackPeaks(rx, tx float64) {
+	s.rxPeak = max(s.rxPeak, rx)
+	s.txPeak = max(s.txPeak, tx)
+	s.rxSum += rx
+	s.txSum += tx
+	s.samples++
+}
+
+// writeSummary prints how long the run lasted, then the peak and average
+// throughput and the bytes moved of every port over the whole run, as an
+// aligned table, followed by the utilization histograms.
+func (m model) writeSummary(w io.Writer) error {
+	nameWidth := len("INTERFACE")
+	for _, stat := range m.statuses {
+		nameWidth = max(nameWidth, len(stat.name()))
+	}
+
+	_, err := fmt.Fprintf(w, "ran %s, %d samples\n", time.Since(m.started).Round(time.Second), m.ticks)
+	if err != nil {
+		return err
+	}
+	rateWidth := max(m.rateWidth(), 9) // never narrower than the default layout
+	const totalWidth = 10              // as formatted by formatBytes
+	_, err = fmt.Fprintf(w, "%-*s  %-*s %-*s %-*s  %-*s %-*s %s\n", nameWidth, "INTERFACE",
+		rateWidth, "RX PEAK", rateWidth, "RX AVG", totalWidth, "RX TOTAL",
+		rateWidth, "TX PEAK", rateWidth, "TX AVG", "TX TOTAL")
+	if err != nil {
+		return err
+	}
+	for _, stat := range m.statuses {
+		var rxAvg, txAvg float64
+		if stat.samples > 0 {
+			rxAvg = stat.rxSum / float64(stat.samples)
+			txAvg = stat.txSum / float64(stat.samples)
+		}
+		_, err := fmt.Fprintf(w, "%-*s  %-*s %-*s %-*s  %-*s %-*s %s\n",
+			nameWidth, stat.name(),
+			rateWidth, m.formatRate(stat.rxPeak), rateWidth, m.formatRate(rxAvg), totalWidth, formatBytes(stat.rxTotal),
+			rateWidth, m.formatRate(stat.txPeak), rateWidth, m.formatRate(txAvg), formatBytes(stat.txTotal))
+		if err != nil {
+			return err
+		}
+	}
+	return m.writeHistograms(w)
+}
+
+// rebase restarts every derived figure of a port from now: the counter
+// baseline, totals, peaks, averages and histogram, and the smoothing,
+// -avg-window and -graph history. The next sample then covers only the
+// time since the reset, so it cannot spike with traffic from before it.
+func (s *ifaceStatus) rebase() {
+	s.rebaseCounters()
+	s.rxValue, s.txValue = 0, 0
+	s.rxHistory, s.txHistory = nil, nil
+	s.rxTotal, s.txTotal = 0, 0
+	s.rxPeak, s.txPeak = 0, 0
+	s.rxSum, s.txSum = 0, 0
+	s.samples = 0
+	s.window.samples = nil
+	s.rxAvg, s.txAvg = 0, 0
+	s.history.samples = nil
+	s.hist = utilHist{}
+	s.wraps = 0
+}
+
+// rebaseCounters makes the port's current counter values the baseline for
+// the next sample, leaving every derived figure alone. The next sample
+// measures from the rebase rather than over a whole interval, so rebasing
+// partway through one, with 'R' or on resuming with 'p', leaves no dip.
+func (s *ifaceStatus) rebaseCounters() {
+	switch {
+	case s.replay != nil:
+		// Recorded rates have no counter baseline.
+	case s.host == nil:
+		s.iface.Rebase()
+	default:
+		if r, at, _, _ := s.host.reading(s.hostIdx); r.ok {
+			s.iface.RebaseTo(r.rx, r.tx)
+			s.readAt = at
+		}
+	}
+}
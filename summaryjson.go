@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/apsu/ibmon/ibmon"
+)
+
+// runSummary is the document -summary-json writes on exit, for benchmark
+// pipelines. Consumers may rely on the field names: add fields, never rename
+// or repurpose them.
+type runSummary struct {
+	Hostname        string        `json:"hostname"`
+	Version         string        `json:"version"`
+	Start           time.Time     `json:"start"`
+	End             time.Time     `json:"end"`
+	IntervalSeconds float64       `json:"interval_seconds"`
+	Samples         int           `json:"samples"`
+	Interfaces      []portSummary `json:"interfaces"`
+}
+
+// portSummary is one port's figures over the run in a runSummary. Rates are
+// in Gbps, or in packets per second for packet counters, as on screen.
+type portSummary struct {
+	Host       string  `json:"host,omitempty"` // remote host, empty for local ports
+	Adaptor    string  `json:"adaptor"`
+	Port       string  `json:"port"`
+	MaxGbps    float64 `json:"max_gbps"`
+	RxAvgGbps  float64 `json:"rx_avg_gbps"`
+	TxAvgGbps  float64 `json:"tx_avg_gbps"`
+	RxPeakGbps float64 `json:"rx_peak_gbps"`
+	TxPeakGbps float64 `json:"tx_peak_gbps"`
+	RxBytes    uint64  `json:"rx_bytes"`
+	TxBytes    uint64  `json:"tx_bytes"`
+
+	// ErrorDeltas is how much each error counter rose over the run, by
+	// sysfs name. It is only present for local ports.
+	ErrorDeltas map[string]int64 `json:"error_deltas,omitempty"`
+}
+
+// readStartErrors records every local port's error counters as the
+// baseline for the -summary-json error deltas.
+func readStartErrors(statuses []ifaceStatus) {
+	for i := range statuses {
+		if stat := &statuses[i]; stat.host == nil && stat.replay == nil {
+			stat.startErrors, _ = stat.iface.ErrorCounters()
+		}
+	}
+}
+
+// runSummary gathers the -summary-json document as of end.
+func (m model) runSummary(end time.Time) runSummary {
+	hostname, _ := os.Hostname()
+	sum := runSummary{
+		Hostname:        hostname,
+		Version:         version,
+		Start:           m.started,
+		End:             end,
+		IntervalSeconds: m.interval.Seconds(),
+		Samples:         m.ticks,
+		Interfaces:      make([]portSummary, 0, len(m.statuses)),
+	}
+	for _, stat := range m.statuses {
+		p := portSummary{
+			Adaptor:    stat.iface.Adaptor,
+			Port:       stat.iface.Port,
+			MaxGbps:    stat.iface.MaxGbps,
+			RxPeakGbps: stat.rxPeak,
+			TxPeakGbps: stat.txPeak,
+			RxBytes:    stat.rxTotal,
+			TxBytes:    stat.txTotal,
+		}
+		p.Host = stat.hostName()
+		if stat.samples > 0 {
+			p.RxAvgGbps = stat.rxSum / float64(stat.samples)
+			p.TxAvgGbps = stat.txSum / float64(stat.samples)
+		}
+		if stat.startErrors != nil {
+			p.ErrorDeltas = errorDeltas(stat.startErrors, stat.iface)
+		}
+		sum.Interfaces = append(sum.Interfaces, p)
+	}
+	return sum
+}
+
+// errorDeltas reads a port's error counters again and returns how far each
+// has risen from start. Counters that can no longer be read are left out.
+func errorDeltas(start []ibmon.Counter, iface ibmon.Interface) map[string]int64 {
+	now, err := iface.ErrorCounters()
+	if err != nil {
+		return nil
+	}
+	before := make(map[string]int64, len(start))
+	for _, c := range start {
+		before[c.Name] = c.Value
+	}
+	deltas := make(map[string]int64, len(now))
+	for _, c := range now {
+		if v, ok := before[c.Name]; ok {
+			deltas[c.Name] = c.Value - v
+		}
+	}
+	return deltas
+}
+
+// writeRunSummary writes the -summary-json document to path.
+func (m model) writeRunSummary(path string, end time.Time) error {
+	data, err := json.MarshalIndent(m.runSummary(end), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// The terminal size assumed when neither a WindowSizeMsg nor the terminal
+// itself says otherwise, e.g. with stdout on a pipe.
+const (
+	defaultTermWidth  = 80
+	defaultTermHeight = 24
+)
+
+// initialSize returns the size to lay the TUI out for until the first
+// WindowSizeMsg, which some environments never send: the size of the
+// terminal on stdout, or the defaults if it reports none. ok is false when
+// the defaults were used.
+func initialSize() (width, height int, ok bool) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return defaultTermWidth, defaultTermHeight, false
+	}
+	return width, height, true
+}
+
+// resize lays the TUI out for a terminal of the given size.
+func (m *model) resize(width, height int) {
+	m.termWidth = width
+	m.termHeight = height
+	m.vp.Width = width
+	m.relayout() // leaves room for the details and footer
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// topSteps are the limits the 'T' key cycles through before showing every
+// port again.
+var topSteps = []int{5, 10, 20}
+
+// shownPorts returns the indices into statuses of the rows drawn in the flat
+// view, in display order, and how many visible ports -top left out. Without
+// -top every visible port is shown in discovery order; with it only the top
+// busiest by combined RX+TX are, busiest first, re-ranked on every render.
+func (m model) shownPorts() (shown []int, more int) {
+	for i, stat := range m.statuses {
+		if m.portVisible(stat) {
+			shown = append(shown, i)
+		}
+	}
+	if m.top <= 0 {
+		return shown, 0
+	}
+	busy := func(i int) float64 {
+		rx, tx := m.statuses[i].displayValues()
+		return rx + tx
+	}
+	// Stable, so equally busy ports (idle ones, typically) keep their order.
+	slices.SortStableFunc(shown, func(a, b int) int {
+		switch ba, bb := busy(a), busy(b); {
+		case ba > bb:
+			return -1
+		case ba < bb:
+			return 1
+		}
+		return 0
+	})
+	if len(shown) <= m.top {
+		return shown, 0
+	}
+	return shown[:m.top], len(shown) - m.top
+}
+
+// cycleTop steps the -top limit through topSteps and back to showing every
+// port.
+func (m *model) cycleTop() {
+	next := 0
+	for _, n := range topSteps {
+		if n > m.top {
+			next = n
+			break
+		}
+	}
+	m.top = next
+	if m.top == 0 {
+		m.setNotice("showing all ports")
+		return
+	}
+	m.setNotice(fmt.Sprintf("showing the %d busiest ports", m.top))
+}
@@ -0,0 +1,39 @@
+package main
+
+import "time"
+
+// stallIntervals is how many intervals may pass between two samples before
+// the footer flags the display as stalled.
+const stallIntervals = 2
+
+// markSampled records that a sample was taken at now, and how long after
+// the previous one.
+func (m *model) markSampled(now time.Time) {
+	if !m.lastSample.IsZero() {
+		m.sampleGap = now.Sub(m.lastSample)
+	}
+	m.lastSample = now
+}
+
+// stalled reports whether sampling has fallen behind at now: the last
+// sample came more than stallIntervals after the one before, typically
+// because a read blocked, or nothing has been sampled for that long since.
+func (m model) stalled(now time.Time) bool {
+	limit := stallIntervals * m.interval
+	return m.sampleGap > limit || (!m.lastSample.IsZero() && now.Sub(m.lastSample) > limit)
+}
+
+// clock returns the footer's uptime and time since the last sample, the
+// latter in red while sampling is stalled, so that a frozen display can be
+// told from a quiet one.
+func (m model) clock(now time.Time) string {
+	up := " • up " + now.Sub(m.started).Truncate(time.Second).String()
+	if m.lastSample.IsZero() {
+		return footerStyle.Render(up + " • no sample yet")
+	}
+	ago := " • updated " + now.Sub(m.lastSample).Truncate(time.Second).String() + " ago"
+	if m.stalled(now) {
+		return footerStyle.Render(up) + warnStyle.Render(ago+", stalled")
+	}
+	return footerStyle.Render(up + ago)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Build information, set at link time, e.g.
+//
+//	go build -ldflags "-X main.version=1.4.0 -X main.commit=$(git rev-parse --short HEAD) -X main.buildDate=$(date -u +%FT%TZ)"
+//
+// Left unset, commit and buildDate fall back to the VCS stamp the go command
+// records when building from a checkout.
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// buildInfo returns the version, commit and build date of the running binary,
+// with "unknown" for whatever was neither set nor stamped.
+func buildInfo() (ver, rev, date string) {
+	rev, date = commit, buildDate
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && rev == "":
+				rev = s.Value
+			case s.Key == "vcs.time" && date == "":
+				date = s.Value
+			}
+		}
+	}
+	if rev == "" {
+		rev = "unknown"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+	return version, rev, date
+}
+
+// versionString is the line printed by -version.
+func versionString() string {
+	ver, rev, date := buildInfo()
+	return fmt.Sprintf("ibmon %s (commit %s, built %s)", ver, rev, date)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSnapshotBuildInfo(t *testing.T) {
+	defer func(v, c, d string) { version, commit, buildDate = v, c, d }(version, commit, buildDate)
+	version, commit, buildDate = "1.4.0", "abc1234", "2024-05-01T12:00:00Z"
+
+	data, err := json.Marshal(model{}.snapshot(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var header struct {
+		Version   string `json:"version"`
+		Commit    string `json:"commit"`
+		BuildDate string `json:"build_date"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != "1.4.0" || header.Commit != "abc1234" || header.BuildDate != "2024-05-01T12:00:00Z" {
+		t.Errorf("snapshot header = %+v, want the -ldflags values", header)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import "time"
+
+// windowSample is one raw reading held in a rateWindow.
+type windowSample struct {
+	at     time.Time
+	rx, tx float64
+}
+
+// rateWindow holds the readings of the last span of wall-clock time, for the
+// -avg-window average. Samples are evicted by timestamp rather than count, so
+// skipped or delayed ticks shrink the window's sample count instead of
+// stretching it past span.
+type rateWindow struct {
+	span    time.Duration
+	samples []windowSample // oldest first
+}
+
+// add appends a reading taken at at and evicts readings that have fallen out
+// of the window.
+func (w *rateWindow) add(at time.Time, rx, tx float64) {
+	w.samples = append(w.samples, windowSample{at: at, rx: rx, tx: tx})
+	w.evict(at)
+}
+
+// evict drops readings taken span or more before now.
+func (w *rateWindow) evict(now time.Time) {
+	cutoff := now.Add(-w.span)
+	i := 0
+	for i < len(w.samples) && !w.samples[i].at.After(cutoff) {
+		i++
+	}
+	if i > 0 {
+		// Copy down rather than reslice so the backing array is reused.
+		w.samples = w.samples[:copy(w.samples, w.samples[i:])]
+	}
+}
+
+// average returns the mean RX and TX of the readings in the window, or zeros
+// if it is empty.
+func (w *rateWindow) average() (rx, tx float64) {
+	if len(w.samples) == 0 {
+		return 0, 0
+	}
+	for _, s := range w.samples {
+		rx += s.rx
+		tx += s.tx
+	}
+	n := float64(len(w.samples))
+	return rx / n, tx / n
+}
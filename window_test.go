@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateWindowEviction(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	w := rateWindow{span: 10 * time.Second}
+
+	// One reading a second for 15s: only the last 10 remain.
+	for i := 0; i < 15; i++ {
+		w.add(start.Add(time.Duration(i)*time.Second), float64(i), 2*float64(i))
+	}
+	if len(w.samples) != 10 {
+		t.Fatalf("got %d samples, want 10", len(w.samples))
+	}
+	if got := w.samples[0].rx; got != 5 {
+		t.Errorf("oldest sample rx = %v, want 5", got)
+	}
+	rx, tx := w.average()
+	if rx != 9.5 || tx != 19 {
+		t.Errorf("average = %v, %v; want 9.5, 19", rx, tx)
+	}
+
+	// A gap longer than the window (a pause, or many skipped ticks) leaves
+	// only the new reading.
+	w.add(start.Add(60*time.Second), 100, 200)
+	if len(w.samples) != 1 {
+		t.Fatalf("after gap got %d samples, want 1", len(w.samples))
+	}
+	rx, tx = w.average()
+	if rx != 100 || tx != 200 {
+		t.Errorf("average after gap = %v, %v; want 100, 200", rx, tx)
+	}
+
+	// A reading exactly span old is evicted.
+	w.add(start.Add(70*time.Second), 0, 0)
+	if len(w.samples) != 1 || w.samples[0].rx != 0 {
+		t.Errorf("sample exactly span old was kept: %+v", w.samples)
+	}
+}
+
+func TestRateWindowEmpty(t *testing.T) {
+	var w rateWindow
+	if rx, tx := w.average(); rx != 0 || tx != 0 {
+		t.Errorf("empty average = %v, %v; want 0, 0", rx, tx)
+	}
+}